@@ -0,0 +1,287 @@
+// Package main contains the bench subcommand for recording and comparing solver performance.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"tetris-optimizer/solver"
+	"tetris-optimizer/tetris"
+)
+
+// minTimeRegression is the smallest slowdown reported as a regression, so that
+// puzzles solved in microseconds do not fail a comparison on timer noise.
+const minTimeRegression = time.Millisecond
+
+// benchPuzzle is a puzzle of the benchmark corpus.
+type benchPuzzle struct {
+	Name  string
+	Path  string
+	Grade string // easy, medium or hard
+}
+
+// benchCorpus lists the benchmark puzzles from easiest to hardest, graded by
+// the nodes the default solver visits.
+var benchCorpus = []benchPuzzle{
+	{"goodexample00-00", "tests/good_examples/goodexample00-00", "easy"},
+	{"goodexample01-09", "tests/good_examples/goodexample01-09", "easy"},
+	{"goodexample02-04", "tests/good_examples/goodexample02-04", "easy"},
+	{"sample00-04", "tests/samples/sample00-04", "medium"},
+	{"goodexample03-05", "tests/good_examples/goodexample03-05", "medium"},
+	{"hardsample-01", "tests/samples/hardsample-01", "hard"},
+	{"sample01-05", "tests/samples/sample01-05", "hard"},
+}
+
+// benchOptions holds the parsed bench command line.
+type benchOptions struct {
+	solver    string
+	runs      int     // Runs per puzzle, the fastest of which is kept
+	record    string  // Baseline file to write, if any
+	compare   string  // Baseline file to compare against, if any
+	threshold float64 // Fraction above the baseline counted as a regression
+}
+
+// BenchResult is the measurement of one puzzle, as stored in a baseline file.
+type BenchResult struct {
+	Name   string `json:"name"`
+	Grade  string `json:"grade"`
+	Size   int    `json:"size"`
+	Nodes  int64  `json:"nodes"`
+	WallNS int64  `json:"wall_ns"`
+}
+
+// Baseline is the document written by bench --record.
+type Baseline struct {
+	Solver  string        `json:"solver"`
+	Results []BenchResult `json:"results"`
+}
+
+// parseBenchArgs parses the bench arguments (without the subcommand name).
+func parseBenchArgs(args []string) (benchOptions, error) {
+	var opts benchOptions
+
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.StringVar(&opts.solver, "solver", solver.DefaultName, "search strategy name")
+	flags.IntVar(&opts.runs, "runs", 3, "runs per puzzle, the fastest is kept")
+	flags.StringVar(&opts.record, "record", "", "write the results to this baseline file")
+	flags.StringVar(&opts.compare, "compare", "", "compare the results with this baseline file")
+	flags.Float64Var(&opts.threshold, "threshold", 0.25, "fraction above the baseline counted as a regression")
+
+	if err := flags.Parse(args); err != nil {
+		return opts, err
+	}
+
+	switch {
+	case flags.NArg() != 0:
+		return opts, errors.New("unexpected arguments")
+	case !slices.Contains(solver.Names(), opts.solver):
+		return opts, fmt.Errorf("unknown solver %q", opts.solver)
+	case opts.runs < 1:
+		return opts, errors.New("runs should be at least 1")
+	case opts.threshold < 0:
+		return opts, errors.New("threshold should not be negative")
+	case opts.record != "" && opts.compare != "":
+		return opts, errors.New("record and compare are mutually exclusive")
+	}
+
+	return opts, nil
+}
+
+// loadBenchPuzzle reads the pieces of a corpus puzzle.
+func loadBenchPuzzle(p benchPuzzle) ([]tetris.Piece, error) {
+	file, err := os.Open(p.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return readPieces(file, false, len(tetris.DefaultAlphabet), ParseOptions{})
+}
+
+// measure solves pieces with a fresh solver and counts the nodes visited.
+func measure(pieces []tetris.Piece, name string) (size int, nodes int64, wall time.Duration, err error) {
+	s, err := solver.New(name)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	start := time.Now()
+	board, stats, err := FindSmallestSquareStats(context.Background(), pieces, s)
+	wall = time.Since(start)
+
+	return board.Width, stats.Total().Nodes, wall, err
+}
+
+// runBench measures every corpus puzzle, keeping the fastest of opts.runs runs.
+// The node count is taken from the same run as the time.
+func runBench(corpus []benchPuzzle, opts benchOptions) ([]BenchResult, error) {
+	results := make([]BenchResult, 0, len(corpus))
+
+	for _, p := range corpus {
+		pieces, err := loadBenchPuzzle(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Name, err)
+		}
+
+		result := BenchResult{Name: p.Name, Grade: p.Grade}
+
+		for run := range opts.runs {
+			size, nodes, wall, err := measure(pieces, opts.solver)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", p.Name, err)
+			}
+
+			if run == 0 || wall.Nanoseconds() < result.WallNS {
+				result.Size, result.Nodes, result.WallNS = size, nodes, wall.Nanoseconds()
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// readBaseline decodes a baseline file.
+func readBaseline(path string) (Baseline, error) {
+	var baseline Baseline
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return baseline, err
+	}
+
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return baseline, fmt.Errorf("invalid baseline %s; %w", path, err)
+	}
+
+	return baseline, nil
+}
+
+// writeBaseline encodes a baseline file.
+func writeBaseline(path string, baseline Baseline) error {
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// regressions returns why result is worse than base beyond threshold, if it is.
+// A different size means the solver is wrong, not slow, and is always reported.
+func regressions(result, base BenchResult, threshold float64) []string {
+	var reasons []string
+
+	if result.Size != base.Size {
+		reasons = append(reasons, fmt.Sprintf("size %d, was %d", result.Size, base.Size))
+	}
+
+	if float64(result.Nodes) > float64(base.Nodes)*(1+threshold) {
+		reasons = append(reasons, fmt.Sprintf("nodes %+.0f%%", percentChange(result.Nodes, base.Nodes)))
+	}
+
+	slower := time.Duration(result.WallNS - base.WallNS)
+	if float64(result.WallNS) > float64(base.WallNS)*(1+threshold) && slower >= minTimeRegression {
+		reasons = append(reasons, fmt.Sprintf("time %+.0f%%", percentChange(result.WallNS, base.WallNS)))
+	}
+
+	return reasons
+}
+
+// percentChange returns the change from base to value in percent.
+func percentChange(value, base int64) float64 {
+	if base == 0 {
+		return 0
+	}
+
+	return 100 * float64(value-base) / float64(base)
+}
+
+// writeBenchTable prints the results, compared with baseline when it is not nil,
+// and returns the number of regressions.
+func writeBenchTable(w io.Writer, results []BenchResult, baseline *Baseline, threshold float64) (int, error) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	count := 0
+
+	if baseline == nil {
+		fmt.Fprintln(tw, "PUZZLE\tGRADE\tSIZE\tNODES\tTIME")
+	} else {
+		fmt.Fprintln(tw, "PUZZLE\tGRADE\tSIZE\tNODES\tTIME\tNODES Δ\tTIME Δ\tSTATUS")
+	}
+
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%v", r.Name, r.Grade, r.Size, r.Nodes, time.Duration(r.WallNS))
+
+		if baseline != nil {
+			i := slices.IndexFunc(baseline.Results, func(b BenchResult) bool { return b.Name == r.Name })
+			if i < 0 {
+				fmt.Fprint(tw, "\t\t\tnew")
+			} else {
+				base := baseline.Results[i]
+				status := "ok"
+
+				if reasons := regressions(r, base, threshold); len(reasons) > 0 {
+					status = fmt.Sprintf("REGRESSION (%s)", strings.Join(reasons, ", "))
+					count++
+				}
+
+				fmt.Fprintf(tw, "\t%+.0f%%\t%+.0f%%\t%s",
+					percentChange(r.Nodes, base.Nodes), percentChange(r.WallNS, base.WallNS), status)
+			}
+		}
+
+		fmt.Fprintln(tw)
+	}
+
+	return count, tw.Flush()
+}
+
+// runBenchCommand measures the corpus, then records or compares a baseline.
+func runBenchCommand(opts benchOptions, w io.Writer) error {
+	var baseline *Baseline
+
+	if opts.compare != "" {
+		b, err := readBaseline(opts.compare)
+		if err != nil {
+			return err
+		}
+
+		if b.Solver != opts.solver {
+			return fmt.Errorf("baseline was recorded with solver %q, not %q", b.Solver, opts.solver)
+		}
+
+		baseline = &b
+	}
+
+	results, err := runBench(benchCorpus, opts)
+	if err != nil {
+		return err
+	}
+
+	count, err := writeBenchTable(w, results, baseline, opts.threshold)
+	if err != nil {
+		return err
+	}
+
+	if opts.record != "" {
+		return writeBaseline(opts.record, Baseline{Solver: opts.solver, Results: results})
+	}
+
+	if count > 0 {
+		return fmt.Errorf("%d puzzles regressed by more than %.0f%%", count, 100*opts.threshold)
+	}
+
+	return nil
+}
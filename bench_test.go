@@ -0,0 +1,112 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBenchArgs(t *testing.T) {
+	testData := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"Defaults", []string{}, false},
+		{"Record", []string{"--record", "base.json", "--runs", "5"}, false},
+		{"Compare", []string{"--compare", "base.json", "--threshold", "0.1", "--solver", "dlx"}, false},
+		{"Both", []string{"--record", "a.json", "--compare", "b.json"}, true},
+		{"Unknown solver", []string{"--solver", "magic"}, true},
+		{"No runs", []string{"--runs", "0"}, true},
+		{"Negative threshold", []string{"--threshold", "-1"}, true},
+		{"Extra argument", []string{"file.txt"}, true},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseBenchArgs(test.args)
+			if (err != nil) != test.wantErr {
+				t.Errorf("expected error %v, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRegressions(t *testing.T) {
+	base := BenchResult{Name: "p", Size: 6, Nodes: 1000, WallNS: int64(100 * time.Millisecond)}
+
+	testData := []struct {
+		name     string
+		result   BenchResult
+		expected []string
+	}{
+		{"Same", base, nil},
+		{"Within threshold", BenchResult{Size: 6, Nodes: 1200, WallNS: int64(120 * time.Millisecond)}, nil},
+		{"Faster", BenchResult{Size: 6, Nodes: 10, WallNS: int64(time.Millisecond)}, nil},
+		{"More nodes", BenchResult{Size: 6, Nodes: 1500, WallNS: base.WallNS}, []string{"nodes +50%"}},
+		{"Slower", BenchResult{Size: 6, Nodes: 1000, WallNS: int64(200 * time.Millisecond)}, []string{"time +100%"}},
+		{"Wrong size", BenchResult{Size: 7, Nodes: 1000, WallNS: base.WallNS}, []string{"size 7, was 6"}},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got := regressions(test.result, base, 0.25)
+			if strings.Join(got, "; ") != strings.Join(test.expected, "; ") {
+				t.Errorf("expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestRegressionsTimerNoise(t *testing.T) {
+	// Tripling a time of a few microseconds is not a regression.
+	base := BenchResult{Size: 2, Nodes: 2, WallNS: int64(10 * time.Microsecond)}
+	result := BenchResult{Size: 2, Nodes: 2, WallNS: int64(30 * time.Microsecond)}
+
+	if got := regressions(result, base, 0.25); len(got) != 0 {
+		t.Errorf("expected no regression, got %q", got)
+	}
+}
+
+func TestBenchBaseline(t *testing.T) {
+	corpus := benchCorpus[:3]
+	opts := benchOptions{solver: "dlx", runs: 1, threshold: 0.25}
+
+	results, err := runBench(corpus, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := writeBaseline(path, Baseline{Solver: opts.solver, Results: results}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	baseline, err := readBaseline(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, r := range baseline.Results {
+		if r.Name != corpus[i].Name || r.Nodes != results[i].Nodes || r.Nodes == 0 {
+			t.Errorf("expected %+v, got %+v", results[i], r)
+		}
+	}
+
+	var sb strings.Builder
+
+	// The same node counts are not a regression, while halving them in the baseline is.
+	count, err := writeBenchTable(&sb, results, &baseline, opts.threshold)
+	if err != nil || count != 0 {
+		t.Errorf("expected no regressions, got %d, %v:\n%s", count, err, sb.String())
+	}
+
+	baseline.Results[2].Nodes /= 2
+	sb.Reset()
+
+	count, err = writeBenchTable(&sb, results, &baseline, opts.threshold)
+	if err != nil || count != 1 || !strings.Contains(sb.String(), "REGRESSION (nodes +100%)") {
+		t.Errorf("expected one node regression, got %d, %v:\n%s", count, err, sb.String())
+	}
+}
@@ -0,0 +1,762 @@
+// Package main contains optimality certificates for square solutions.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"tetris-optimizer/tetris"
+)
+
+// certificateHeader is the first line of a certificate file.
+const certificateHeader = "tetris-optimizer certificate 2"
+
+// Reason explains why a square one smaller than the solution cannot hold the pieces.
+type Reason string
+
+const (
+	ReasonArea   Reason = "area"   // The pieces have more cells than the square
+	ReasonLength Reason = "length" // Some piece is longer than the square in every orientation
+	ReasonSearch Reason = "search" // Every placement was refuted, as recorded in the tree
+)
+
+// Leaf codes in a refutation tree, for nodes cut off without branching.
+const (
+	leafParity = -1 // The checkerboard colours of the free cells cannot take the pieces left
+	leafDead   = -2 // More free cells lie in pockets too small for any piece than are spare
+)
+
+// Certificate is a proof that pieces do not fit in a square of size Size-1,
+// so a solution of size Size is optimal.
+//
+// A search proof is a refutation tree. Each node branches on one unplaced
+// piece and has a child for every placement of it, in the order they are
+// enumerated on the board: orientation, then row, then column. A node whose
+// piece has no placement is a dead end, and so is a node holding a leaf code,
+// whose check rules out the board as it stands.
+//
+// Identical pieces are interchangeable, so they are all enumerated in the
+// orientation order of the first of them, and once one is placed at some
+// rank in that order, they skip the lower ranks for the rest of its subtree:
+// a packing using one of those was refuted by an earlier sibling.
+//
+// Tree holds the branching piece or leaf code of each node in preorder; the
+// children are implied, so a checker only needs the pieces to rebuild and
+// refute every branch.
+type Certificate struct {
+	Size       int              // Size of the solution
+	Transforms tetris.Transform // Symmetries the pieces were placed under
+	Reason     Reason
+	Tree       []int // Branching piece index of each node, in preorder
+	Leaves     int   // Number of dead ends in Tree
+}
+
+// Certify returns a certificate that the pieces do not fit in a square of size-1.
+// It reports an error if they do fit, or if ctx is done first.
+func Certify(ctx context.Context, pieces []tetris.Piece, size int) (Certificate, error) {
+	if size < 1 {
+		return Certificate{}, errors.New("an empty board needs no certificate")
+	}
+
+	c := Certificate{Size: size}
+	if len(pieces) > 0 {
+		c.Transforms = pieces[0].Transforms
+	}
+
+	side := size - 1
+
+	if pieceCells(pieces) > side*side {
+		c.Reason = ReasonArea
+		return c, nil
+	}
+
+	if tooLong(pieces, side) {
+		c.Reason = ReasonLength
+		return c, nil
+	}
+
+	r := refuter{
+		board:  tetris.NewBoard(uint(side)),
+		pieces: pieces,
+		placed: make([]bool, len(pieces)),
+		done:   ctx.Done(),
+	}
+
+	r.orientations, r.group, r.floor = groupOrientations(pieces)
+
+	if !r.refute() {
+		if err := ctx.Err(); r.cancelled && err != nil {
+			return Certificate{}, fmt.Errorf("%w while certifying size %d: %w", ErrInterrupted, side, err)
+		}
+
+		return Certificate{}, fmt.Errorf("pieces fit in a square of size %d", side)
+	}
+
+	c.Reason = ReasonSearch
+	c.Tree = r.tree
+	c.Leaves = r.leaves
+
+	return c, nil
+}
+
+// tooLong reports whether some piece fits a side×side square in none of its orientations.
+func tooLong(pieces []tetris.Piece, side int) bool {
+	for _, p := range pieces {
+		fits := false
+
+		for _, o := range p.Orientations() {
+			if o.Width <= side && o.Height <= side {
+				fits = true
+				break
+			}
+		}
+
+		if !fits {
+			return true
+		}
+	}
+
+	return false
+}
+
+// refuter builds a refutation tree, branching on the piece with the fewest placements.
+type refuter struct {
+	board        tetris.Board
+	pieces       []tetris.Piece
+	orientations [][]tetris.Piece
+	group        []int // Identical group of each piece
+	floor        []int // Lowest rank each group may still be placed at
+	placed       []bool
+	tree         []int
+	leaves       int
+	done         <-chan struct{}
+	cancelled    bool
+	ops          int // Operation counter to throttle cancellation checks
+}
+
+// placements returns the number of placements of piece i on the board, stopping at limit.
+func (r *refuter) placements(i, limit int) int {
+	count := 0
+	floor := r.floor[r.group[i]]
+
+	for oi, o := range r.orientations[i] {
+		for y := 0; y <= r.board.Height-o.Height; y++ {
+			for x := 0; x <= r.board.Width-o.Width; x++ {
+				if rank(r.board.Width, oi, x, y) >= floor && r.board.CanPlace(o, x, y) {
+					count++
+					if count >= limit {
+						return count
+					}
+				}
+			}
+		}
+	}
+
+	return count
+}
+
+// refute extends the tree below the current board. It returns false if the
+// remaining pieces can all be placed, or if the search was cancelled.
+func (r *refuter) refute() bool {
+	r.ops++
+	if r.ops&1023 == 0 && !r.cancelled {
+		select {
+		case <-r.done:
+			r.cancelled = true
+		default:
+		}
+	}
+
+	if r.cancelled {
+		return false
+	}
+
+	// OPTIMIZATION: Cut boards the parity and dead-region checks rule out, as the solvers do.
+	if leaf := cutLeaf(r.board.Width, r.board.Empty, unplaced(r.pieces, r.placed)); leaf != 0 {
+		r.tree = append(r.tree, leaf)
+		r.leaves++
+		return true
+	}
+
+	// OPTIMIZATION: Branch on the most constrained piece to keep the tree small.
+	branch, fewest := -1, 0
+
+	for i := range r.orientations {
+		if r.placed[i] {
+			continue
+		}
+
+		limit := fewest
+		if branch < 0 {
+			limit = int(^uint(0) >> 1)
+		}
+
+		if count := r.placements(i, limit); branch < 0 || count < fewest {
+			branch, fewest = i, count
+		}
+
+		if fewest == 0 {
+			break
+		}
+	}
+
+	if branch < 0 {
+		return false // Every piece is placed.
+	}
+
+	r.tree = append(r.tree, branch)
+	if fewest == 0 {
+		r.leaves++
+		return true
+	}
+
+	g := r.group[branch]
+	floor := r.floor[g]
+	r.placed[branch] = true
+
+	defer func() {
+		r.placed[branch] = false
+		r.floor[g] = floor
+	}()
+
+	for oi, o := range r.orientations[branch] {
+		for y := 0; y <= r.board.Height-o.Height; y++ {
+			for x := 0; x <= r.board.Width-o.Width; x++ {
+				at := rank(r.board.Width, oi, x, y)
+				if at < floor || !r.board.CanPlace(o, x, y) {
+					continue
+				}
+
+				r.board.Place(o, x, y)
+				r.floor[g] = at + 1
+				ok := r.refute()
+				r.board.Remove(o, x, y)
+
+				if !ok {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// groupOrientations returns the orientations each piece is enumerated in, which
+// are those of the first piece identical to it, with the index of its identical
+// group and a zero floor for each group.
+func groupOrientations(pieces []tetris.Piece) (orientations [][]tetris.Piece, group, floor []int) {
+	groups := tetris.IdenticalGroups(pieces)
+	orientations = make([][]tetris.Piece, len(pieces))
+	group = make([]int, len(pieces))
+
+	for g, members := range groups {
+		leader := pieces[members[0]].Orientations()
+
+		for _, i := range members {
+			orientations[i] = leader
+			group[i] = g
+		}
+	}
+
+	return orientations, group, make([]int, len(groups))
+}
+
+// rank orders the placements of a piece at orientation o and (x, y) on a square of side side.
+func rank(side, o, x, y int) int {
+	return (o*side+y)*side + x
+}
+
+// unplaced returns the pieces not marked as placed.
+func unplaced(pieces []tetris.Piece, placed []bool) []tetris.Piece {
+	var out []tetris.Piece
+
+	for i, p := range pieces {
+		if !placed[i] {
+			out = append(out, p)
+		}
+	}
+
+	return out
+}
+
+// cutLeaf returns the leaf code of the first check ruling out placing the
+// remaining pieces on the free cells of a side×side square, or 0 if neither does.
+func cutLeaf(side int, free func(x, y int) bool, remaining []tetris.Piece) int {
+	for _, leaf := range []int{leafParity, leafDead} {
+		if cutHolds(leaf, side, free, remaining) {
+			return leaf
+		}
+	}
+
+	return 0
+}
+
+// cutHolds reports whether the check of leaf rules out placing the remaining pieces.
+func cutHolds(leaf, side int, free func(x, y int) bool, remaining []tetris.Piece) bool {
+	if len(remaining) == 0 {
+		return false
+	}
+
+	if leaf == leafParity {
+		return !parityFits(side, free, remaining)
+	}
+
+	return !deadFits(side, free, remaining)
+}
+
+// parityFits reports whether the checkerboard colouring of the free cells can
+// take the remaining pieces. A piece covers as many cells of one colour as its
+// blocks of one parity, or of the other if it is moved by one cell, in any
+// orientation, so the covered cells of each colour must be a sum of one of the
+// two counts of every piece.
+func parityFits(side int, free func(x, y int) bool, remaining []tetris.Piece) bool {
+	freeEven, freeOdd := 0, 0
+
+	for y := range side {
+		for x := range side {
+			switch {
+			case !free(x, y):
+			case (x+y)%2 == 0:
+				freeEven++
+			default:
+				freeOdd++
+			}
+		}
+	}
+
+	// reachable[s] holds whether the pieces can cover least+s even cells.
+	least, total := 0, 0
+	reachable := []bool{true}
+
+	for _, p := range remaining {
+		even := 0
+
+		for _, b := range p.Pos {
+			if (b.X+b.Y)%2 == 0 {
+				even++
+			}
+		}
+
+		odd := len(p.Pos) - even
+		least += min(even, odd)
+		total += len(p.Pos)
+		extra := max(even, odd) - min(even, odd)
+
+		next := make([]bool, len(reachable)+extra)
+		for s, ok := range reachable {
+			if ok {
+				next[s] = true
+				next[s+extra] = true
+			}
+		}
+
+		reachable = next
+	}
+
+	for s, ok := range reachable {
+		if even := least + s; ok && even <= freeEven && total-even <= freeOdd {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deadFits reports whether the free cells in regions smaller than the
+// smallest remaining piece, which no piece can use, fit in the free cells
+// the remaining pieces leave over.
+func deadFits(side int, free func(x, y int) bool, remaining []tetris.Piece) bool {
+	smallest, spare := len(remaining[0].Pos), 0
+
+	for _, p := range remaining {
+		smallest = min(smallest, len(p.Pos))
+		spare -= len(p.Pos)
+	}
+
+	seen := make([]bool, side*side)
+	var stack []tetris.Point
+
+	for y := range side {
+		for x := range side {
+			if free(x, y) {
+				spare++
+			}
+		}
+	}
+
+	for y := range side {
+		for x := range side {
+			if !free(x, y) || seen[y*side+x] {
+				continue
+			}
+
+			size := 0
+			seen[y*side+x] = true
+			stack = append(stack[:0], tetris.Point{X: x, Y: y})
+
+			for len(stack) > 0 {
+				cell := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				size++
+
+				for _, n := range neighbours(cell.X, cell.Y) {
+					nx, ny := n[0], n[1]
+					if nx >= 0 && ny >= 0 && nx < side && ny < side && free(nx, ny) && !seen[ny*side+nx] {
+						seen[ny*side+nx] = true
+						stack = append(stack, tetris.Point{X: nx, Y: ny})
+					}
+				}
+			}
+
+			if size < smallest {
+				spare -= size
+			}
+		}
+	}
+
+	return spare >= 0
+}
+
+// VerifyCertificate checks that c proves the pieces do not fit in a square of size c.Size-1.
+// The check does not use any solver: search proofs are replayed on a plain grid.
+func VerifyCertificate(pieces []tetris.Piece, c Certificate) error {
+	if c.Size < 1 {
+		return errors.New("certificate size should be at least 1")
+	}
+
+	pieces = withTransforms(pieces, c.Transforms)
+	side := c.Size - 1
+
+	switch c.Reason {
+	case ReasonArea:
+		if pieceCells(pieces) <= side*side {
+			return fmt.Errorf("%d cells fit in the area of a square of size %d", pieceCells(pieces), side)
+		}
+	case ReasonLength:
+		if !tooLong(pieces, side) {
+			return fmt.Errorf("every piece fits in a square of size %d", side)
+		}
+	case ReasonSearch:
+		return verifyTree(pieces, c, side)
+	default:
+		return fmt.Errorf("unknown certificate reason %q", c.Reason)
+	}
+
+	return nil
+}
+
+// withTransforms returns a copy of pieces that may be placed under transforms.
+func withTransforms(pieces []tetris.Piece, transforms tetris.Transform) []tetris.Piece {
+	out := make([]tetris.Piece, len(pieces))
+
+	for i, p := range pieces {
+		p.Transforms = transforms
+		out[i] = p
+	}
+
+	return out
+}
+
+// treeChecker replays a refutation tree on a plain grid.
+type treeChecker struct {
+	side         int
+	cells        [][]bool
+	pieces       []tetris.Piece
+	orientations [][]tetris.Piece
+	group        []int
+	floor        []int
+	placed       []bool
+	tree         []int
+	next         int // Index of the next node in tree
+	leaves       int
+}
+
+// verifyTree checks the search proof of c on a side×side square.
+func verifyTree(pieces []tetris.Piece, c Certificate, side int) error {
+	checker := treeChecker{
+		side:   side,
+		cells:  make([][]bool, side),
+		pieces: pieces,
+		placed: make([]bool, len(pieces)),
+		tree:   c.Tree,
+	}
+
+	for y := range checker.cells {
+		checker.cells[y] = make([]bool, side)
+	}
+
+	checker.orientations, checker.group, checker.floor = groupOrientations(pieces)
+
+	if err := checker.node(); err != nil {
+		return err
+	}
+
+	if checker.next != len(c.Tree) {
+		return fmt.Errorf("certificate has %d nodes after the end of the tree", len(c.Tree)-checker.next)
+	}
+
+	if checker.leaves != c.Leaves {
+		return fmt.Errorf("certificate claims %d dead ends, tree has %d", c.Leaves, checker.leaves)
+	}
+
+	return nil
+}
+
+// fits reports whether o can be placed with its top-left corner at (x, y).
+func (tc *treeChecker) fits(o tetris.Piece, x, y int) bool {
+	for _, p := range o.Pos {
+		if x+p.X >= tc.side || y+p.Y >= tc.side || tc.cells[y+p.Y][x+p.X] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// set fills or clears the cells of o placed at (x, y).
+func (tc *treeChecker) set(o tetris.Piece, x, y int, filled bool) {
+	for _, p := range o.Pos {
+		tc.cells[y+p.Y][x+p.X] = filled
+	}
+}
+
+// node checks the subtree starting at the next node of the tree.
+func (tc *treeChecker) node() error {
+	if tc.next >= len(tc.tree) {
+		return errors.New("certificate tree ends early")
+	}
+
+	index := tc.next
+	branch := tc.tree[index]
+	tc.next++
+
+	if branch == leafParity || branch == leafDead {
+		free := func(x, y int) bool { return !tc.cells[y][x] }
+
+		if !cutHolds(branch, tc.side, free, unplaced(tc.pieces, tc.placed)) {
+			return fmt.Errorf("node %d claims a %s cut that does not hold", index, leafName(branch))
+		}
+
+		tc.leaves++
+		return nil
+	}
+
+	if branch < 0 || branch >= len(tc.placed) || tc.placed[branch] {
+		return fmt.Errorf("node %d branches on invalid piece %d", index, branch)
+	}
+
+	g := tc.group[branch]
+	floor := tc.floor[g]
+	tc.placed[branch] = true
+
+	defer func() {
+		tc.placed[branch] = false
+		tc.floor[g] = floor
+	}()
+
+	children := 0
+
+	for oi, o := range tc.orientations[branch] {
+		for y := 0; y+o.Height <= tc.side; y++ {
+			for x := 0; x+o.Width <= tc.side; x++ {
+				at := rank(tc.side, oi, x, y)
+				if at < floor || !tc.fits(o, x, y) {
+					continue
+				}
+
+				children++
+
+				if tc.done() {
+					return fmt.Errorf("node %d leads to a full packing", index)
+				}
+
+				tc.set(o, x, y, true)
+				tc.floor[g] = at + 1
+				err := tc.node()
+				tc.set(o, x, y, false)
+
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if children == 0 {
+		tc.leaves++
+	}
+
+	return nil
+}
+
+// leafName returns the name of a leaf code in errors.
+func leafName(leaf int) string {
+	if leaf == leafParity {
+		return "parity"
+	}
+
+	return "dead-region"
+}
+
+// done reports whether every piece is placed.
+func (tc *treeChecker) done() bool {
+	for _, placed := range tc.placed {
+		if !placed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// formatTransforms returns the certificate spelling of transforms.
+func formatTransforms(transforms tetris.Transform) string {
+	var names []string
+
+	if transforms&tetris.Rotate != 0 {
+		names = append(names, "rotate")
+	}
+
+	if transforms&tetris.Mirror != 0 {
+		names = append(names, "mirror")
+	}
+
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, ",")
+}
+
+// parseTransforms is the inverse of formatTransforms.
+func parseTransforms(s string) (tetris.Transform, error) {
+	var transforms tetris.Transform
+
+	if s == "none" {
+		return 0, nil
+	}
+
+	for _, name := range strings.Split(s, ",") {
+		switch name {
+		case "rotate":
+			transforms |= tetris.Rotate
+		case "mirror":
+			transforms |= tetris.Mirror
+		default:
+			return 0, fmt.Errorf("unknown transform %q", name)
+		}
+	}
+
+	return transforms, nil
+}
+
+// WriteCertificate writes c in the text certificate format.
+func WriteCertificate(w io.Writer, c Certificate) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, certificateHeader)
+	fmt.Fprintf(bw, "size %d\n", c.Size)
+	fmt.Fprintf(bw, "transforms %s\n", formatTransforms(c.Transforms))
+	fmt.Fprintf(bw, "reason %s\n", c.Reason)
+
+	if c.Reason == ReasonSearch {
+		fmt.Fprintf(bw, "nodes %d\n", len(c.Tree))
+		fmt.Fprintf(bw, "leaves %d\n", c.Leaves)
+		fmt.Fprintln(bw, "tree")
+
+		// Twenty nodes to a line keeps large trees readable in a pager.
+		for i, branch := range c.Tree {
+			if i%20 != 0 {
+				bw.WriteByte(' ')
+			}
+
+			bw.WriteString(strconv.Itoa(branch))
+
+			if i%20 == 19 || i == len(c.Tree)-1 {
+				bw.WriteByte('\n')
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// ReadCertificate parses a certificate written by WriteCertificate.
+func ReadCertificate(r io.Reader) (Certificate, error) {
+	var c Certificate
+
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || scanner.Text() != certificateHeader {
+		if err := scanner.Err(); err != nil {
+			return c, err
+		}
+
+		return c, errors.New("invalid certificate; missing header")
+	}
+
+	nodes := -1
+	inTree := false
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		if inTree {
+			for _, field := range fields {
+				branch, err := strconv.Atoi(field)
+				if err != nil {
+					return c, fmt.Errorf("invalid certificate; bad tree node %q", field)
+				}
+
+				c.Tree = append(c.Tree, branch)
+			}
+
+			continue
+		}
+
+		if len(fields) == 1 && fields[0] == "tree" {
+			inTree = true
+			continue
+		}
+
+		if len(fields) != 2 {
+			return c, fmt.Errorf("invalid certificate; bad line %q", scanner.Text())
+		}
+
+		var err error
+
+		switch key, value := fields[0], fields[1]; key {
+		case "size":
+			c.Size, err = strconv.Atoi(value)
+		case "transforms":
+			c.Transforms, err = parseTransforms(value)
+		case "reason":
+			c.Reason = Reason(value)
+		case "nodes":
+			nodes, err = strconv.Atoi(value)
+		case "leaves":
+			c.Leaves, err = strconv.Atoi(value)
+		default:
+			err = fmt.Errorf("unknown key %q", key)
+		}
+
+		if err != nil {
+			return c, fmt.Errorf("invalid certificate; %w", err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return c, err
+	}
+
+	if c.Reason == ReasonSearch && nodes != len(c.Tree) {
+		return c, fmt.Errorf("invalid certificate; expected %d nodes, got %d", nodes, len(c.Tree))
+	}
+
+	return c, nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"tetris-optimizer/tetris"
+)
+
+// fourLs returns four L tetrominoes placed under transforms.
+func fourLs(t *testing.T, transforms tetris.Transform) []tetris.Piece {
+	t.Helper()
+
+	pieces, err := readPieces(strings.NewReader(strings.Repeat("#...\n#...\n##..\n....\n\n", 4)), false, 0, ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return withTransforms(pieces, transforms)
+}
+
+func TestCertify(t *testing.T) {
+	onlyI, err := readPieces(strings.NewReader("####\n....\n....\n....\n"), false, 0, ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testData := []struct {
+		name     string
+		pieces   []tetris.Piece
+		size     int
+		expected Reason
+	}{
+		{"Area", loadPieces(t, "tests/samples/sample00-04"), 6, ReasonArea},
+		{"Length", onlyI, 4, ReasonLength},
+		{"Search", loadPieces(t, "tests/good_examples/goodexample01-09"), 5, ReasonSearch},
+		{"Search with many dead ends", fourLs(t, 0), 6, ReasonSearch},
+		{"Rotated", fourLs(t, tetris.Rotate), 4, ReasonArea},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			cert, err := Certify(context.Background(), test.pieces, test.size)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if cert.Reason != test.expected || cert.Size != test.size {
+				t.Fatalf("expected %s proof for size %d, got %+v", test.expected, test.size, cert)
+			}
+
+			if cert.Reason == ReasonSearch && (len(cert.Tree) == 0 || cert.Leaves == 0) {
+				t.Fatalf("expected a non-empty refutation tree, got %+v", cert)
+			}
+
+			if err := VerifyCertificate(test.pieces, cert); err != nil {
+				t.Fatalf("expected certificate to verify, got %v", err)
+			}
+		})
+	}
+
+	t.Run("Feasible smaller square", func(t *testing.T) {
+		expectedMsg := "pieces fit in a square of size 5"
+		_, err := Certify(context.Background(), loadPieces(t, "tests/good_examples/goodexample01-09"), 6)
+
+		if err == nil || err.Error() != expectedMsg {
+			t.Fatalf("expected error %q, got %v", expectedMsg, err)
+		}
+	})
+}
+
+func TestCertifyPruned(t *testing.T) {
+	testData := []struct {
+		seed   string
+		leaves int // Expected dead ends, or 0 for any
+	}{
+		{"126", 1},
+		{"134", 0},
+		{"142", 1},
+	}
+
+	for _, test := range testData {
+		t.Run("Seed "+test.seed, func(t *testing.T) {
+			_, pieces := generatePuzzle(t, "--pieces", "9", "--shapes", "S,Z,T", "--seed", test.seed)
+			pieces = withTransforms(pieces, tetris.Rotate|tetris.Mirror)
+
+			board, err := FindSmallestSquare(context.Background(), pieces, newSolver(defaultOptions(nil)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			cert, err := Certify(ctx, pieces, board.Width)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if cert.Reason != ReasonSearch || (test.leaves != 0 && cert.Leaves != test.leaves) {
+				t.Fatalf("expected a search proof with %d dead ends, got %s with %d", test.leaves, cert.Reason, cert.Leaves)
+			}
+
+			if err := VerifyCertificate(pieces, cert); err != nil {
+				t.Fatalf("expected certificate to verify, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyCertificateRejects(t *testing.T) {
+	pieces := fourLs(t, 0)
+	cert, err := Certify(context.Background(), pieces, 6)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testData := []struct {
+		name   string
+		edit   func(c *Certificate)
+		errMsg string
+	}{
+		{"Wrong reason", func(c *Certificate) { c.Reason = ReasonArea }, "16 cells fit in the area of a square of size 5"},
+		{"Wrong length", func(c *Certificate) { c.Reason = ReasonLength }, "every piece fits in a square of size 5"},
+		{"Unknown reason", func(c *Certificate) { c.Reason = "magic" }, `unknown certificate reason "magic"`},
+		{"Truncated tree", func(c *Certificate) { c.Tree = c.Tree[:len(c.Tree)-1] }, "certificate tree ends early"},
+		{"Extra nodes", func(c *Certificate) { c.Tree = append(c.Tree, 0) }, "certificate has 1 nodes after the end of the tree"},
+		{"Wrong leaves", func(c *Certificate) { c.Leaves++ }, ""},
+		{"Repeated piece", func(c *Certificate) { c.Tree[1] = c.Tree[0] }, ""},
+		{"Rotation allowed", func(c *Certificate) { c.Transforms = tetris.Rotate }, ""},
+		{
+			"Forged parity cut", func(c *Certificate) { c.Tree, c.Leaves = []int{leafParity}, 1 },
+			"node 0 claims a parity cut that does not hold",
+		},
+		{
+			"Forged dead-region cut", func(c *Certificate) { c.Tree, c.Leaves = []int{leafDead}, 1 },
+			"node 0 claims a dead-region cut that does not hold",
+		},
+		{"Unknown leaf code", func(c *Certificate) { c.Tree, c.Leaves = []int{-3}, 1 }, "node 0 branches on invalid piece -3"},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			tampered := cert
+			tampered.Tree = slices.Clone(cert.Tree)
+			test.edit(&tampered)
+
+			err := VerifyCertificate(pieces, tampered)
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+
+			if test.errMsg != "" && err.Error() != test.errMsg {
+				t.Errorf("expected error %q, got %q", test.errMsg, err.Error())
+			}
+		})
+	}
+}
+
+func TestCertificateRoundTrip(t *testing.T) {
+	testData := []Certificate{
+		{Size: 6, Reason: ReasonArea},
+		{Size: 4, Transforms: tetris.Rotate | tetris.Mirror, Reason: ReasonLength},
+		{Size: 5, Reason: ReasonSearch, Tree: []int{0, 1, 1, 1, 1}, Leaves: 4},
+		{Size: 7, Reason: ReasonSearch, Tree: []int{2, leafParity, 0, leafDead}, Leaves: 2},
+	}
+
+	cert, err := Certify(context.Background(), fourLs(t, 0), 6)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testData = append(testData, cert)
+
+	for _, c := range testData {
+		var buf bytes.Buffer
+
+		if err := WriteCertificate(&buf, c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		got, err := ReadCertificate(&buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(got, c) {
+			t.Errorf("expected %+v, got %+v", c, got)
+		}
+	}
+}
+
+func TestReadCertificateInvalid(t *testing.T) {
+	testData := []struct {
+		name   string
+		input  string
+		errMsg string
+	}{
+		{"Missing header", "size 5\n", "invalid certificate; missing header"},
+		{"Unknown key", certificateHeader + "\ncolour red\n", `invalid certificate; unknown key "colour"`},
+		{"Bad size", certificateHeader + "\nsize five\n", ""},
+		{"Bad transform", certificateHeader + "\ntransforms shear\n", `invalid certificate; unknown transform "shear"`},
+		{"Bad node", certificateHeader + "\nreason search\nnodes 1\nleaves 1\ntree\nx\n", `invalid certificate; bad tree node "x"`},
+		{
+			"Node count", certificateHeader + "\nreason search\nnodes 2\nleaves 1\ntree\n0\n",
+			"invalid certificate; expected 2 nodes, got 1",
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadCertificate(strings.NewReader(test.input))
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+
+			if test.errMsg != "" && err.Error() != test.errMsg {
+				t.Errorf("expected error %q, got %q", test.errMsg, err.Error())
+			}
+		})
+	}
+}
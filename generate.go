@@ -0,0 +1,389 @@
+// Package main contains the generate subcommand for random puzzle files.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+
+	"tetris-optimizer/tetris"
+)
+
+// Bounds on the search for a tiling in perfect mode.
+const (
+	maxCutNodes         = 250_000 // Over all attempts
+	restartNodesPerCell = 4       // Per attempt, for each cell of the square
+	maxPocket           = 64      // Largest region of empty cells checked for a dead end
+)
+
+// generateOptions holds the parsed generate command line.
+type generateOptions struct {
+	pieces  int                  // Number of pieces in random mode
+	perfect int                  // Side of the square to cut into pieces, 0 for random mode
+	weights map[tetris.Shape]int // Relative frequency of each shape
+	seed    uint64
+	seeded  bool // Whether the seed was given, rather than to be picked from the clock
+}
+
+// parseShapeWeights parses a shape distribution such as "T=3,L=2,O=1".
+// Shapes left out do not appear, and each shape may be given once. An empty
+// string gives every shape weight 1.
+func parseShapeWeights(s string) (map[tetris.Shape]int, error) {
+	weights := map[tetris.Shape]int{}
+
+	if s == "" {
+		for shape := tetris.ShapeI; shape <= tetris.ShapeL; shape++ {
+			weights[shape] = 1
+		}
+
+		return weights, nil
+	}
+
+	total := 0
+
+	for _, part := range strings.Split(s, ",") {
+		name, value, found := strings.Cut(part, "=")
+		if !found {
+			value = "1"
+		}
+
+		shape := tetris.NoShape
+		for candidate := tetris.ShapeI; candidate <= tetris.ShapeL; candidate++ {
+			if strings.EqualFold(name, candidate.String()) {
+				shape = candidate
+			}
+		}
+
+		if shape == tetris.NoShape {
+			return nil, fmt.Errorf("unknown shape %q", name)
+		}
+
+		if _, repeated := weights[shape]; repeated {
+			return nil, fmt.Errorf("shape %v given more than once", shape)
+		}
+
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("weight of %v should be a non-negative integer", shape)
+		}
+
+		weights[shape] = weight
+		total += weight
+	}
+
+	if total == 0 {
+		return nil, errors.New("at least one shape should have a positive weight")
+	}
+
+	return weights, nil
+}
+
+// parseGenerateArgs parses the generate arguments (without the subcommand name).
+func parseGenerateArgs(args []string) (generateOptions, error) {
+	var opts generateOptions
+
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.IntVar(&opts.pieces, "pieces", 0, "number of random pieces")
+	flags.IntVar(&opts.perfect, "perfect", 0, "cut a square of this side into pieces")
+	shapes := flags.String("shapes", "", "shape distribution, e.g. T=3,L=2,O=1")
+	flags.Uint64Var(&opts.seed, "seed", 0, "random seed, picked from the clock if not given")
+
+	if err := flags.Parse(args); err != nil {
+		return opts, err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		opts.seeded = opts.seeded || f.Name == "seed"
+	})
+
+	if flags.NArg() != 0 {
+		return opts, errors.New("unexpected arguments")
+	}
+
+	switch {
+	case opts.pieces != 0 && opts.perfect != 0:
+		return opts, errors.New("perfect puzzles take their number of pieces from the square")
+	case opts.perfect != 0 && (opts.perfect%2 != 0 || opts.perfect < 2 || opts.perfect > tetris.MaxSize):
+		return opts, fmt.Errorf("perfect square side should be even and between 2 and %d", tetris.MaxSize)
+	case opts.perfect == 0 && opts.pieces < 1:
+		return opts, errors.New("expected a positive number of pieces or a perfect square side")
+	}
+
+	weights, err := parseShapeWeights(*shapes)
+	if err != nil {
+		return opts, err
+	}
+
+	opts.weights = weights
+	return opts, nil
+}
+
+// weightedShapes returns the shapes with a positive weight in a random order,
+// each drawn with a probability proportional to its weight among those left.
+func weightedShapes(rng *rand.Rand, weights map[tetris.Shape]int) []tetris.Shape {
+	var shapes []tetris.Shape
+
+	total := 0
+
+	for shape := tetris.ShapeI; shape <= tetris.ShapeL; shape++ {
+		if weights[shape] > 0 {
+			shapes = append(shapes, shape)
+			total += weights[shape]
+		}
+	}
+
+	for i := range shapes {
+		pick := rng.IntN(total)
+
+		for j := i; j < len(shapes); j++ {
+			if pick < weights[shapes[j]] {
+				shapes[i], shapes[j] = shapes[j], shapes[i]
+				break
+			}
+
+			pick -= weights[shapes[j]]
+		}
+
+		total -= weights[shapes[i]]
+	}
+
+	return shapes
+}
+
+// randomPieces draws n pieces from the weighted shapes, each in a random orientation.
+func randomPieces(rng *rand.Rand, n int, weights map[tetris.Shape]int) []tetris.Piece {
+	pieces := make([]tetris.Piece, n)
+
+	for i := range pieces {
+		orientations := weightedShapes(rng, weights)[0].Orientations()
+		pieces[i] = orientations[rng.IntN(len(orientations))]
+	}
+
+	return pieces
+}
+
+// squareCutter tiles a square with tetrominoes, filling the first empty cell in
+// row-major order with a random orientation of a weighted random shape.
+type squareCutter struct {
+	rng          *rand.Rand
+	weights      map[tetris.Shape]int
+	orientations map[tetris.Shape][]tetris.Piece
+	size         int
+	cells        [][]bool
+	pieces       []tetris.Piece
+	nodes        int      // Nodes searched over all attempts
+	limit        int      // Node count at which the current attempt gives up
+	seen         []int    // Stamp of the last flood fill that reached each cell
+	stamp        int      // Stamp of the current flood fill
+	stack        [][2]int // Flood fill stack, kept to save allocations
+}
+
+// cut fills the square from the first empty cell on, reporting false on a dead end
+// or once the node limit is reached.
+func (c *squareCutter) cut(from int) bool {
+	for from < c.size*c.size && c.cells[from/c.size][from%c.size] {
+		from++
+	}
+
+	if from == c.size*c.size {
+		return true
+	}
+
+	c.nodes++
+	if c.nodes > c.limit {
+		return false
+	}
+
+	x, y := from%c.size, from/c.size
+
+	for _, shape := range weightedShapes(c.rng, c.weights) {
+		orientations := c.orientations[shape]
+
+		for _, i := range c.rng.Perm(len(orientations)) {
+			o := orientations[i]
+
+			// The first block in row-major order goes on the empty cell.
+			originX, originY := x-o.Pos[0].X, y-o.Pos[0].Y
+			if !c.fits(o, originX, originY) {
+				continue
+			}
+
+			c.set(o, originX, originY, true)
+			c.pieces = append(c.pieces, o)
+
+			if !c.leavesPocket(o, originX, originY) && c.cut(from+1) {
+				return true
+			}
+
+			c.pieces = c.pieces[:len(c.pieces)-1]
+			c.set(o, originX, originY, false)
+		}
+	}
+
+	return false
+}
+
+// leavesPocket reports whether piece p placed at (x, y) closes off a small
+// region of empty cells that no set of tetrominoes fills, as its size is not a
+// multiple of 4. Regions larger than maxPocket are not checked.
+func (c *squareCutter) leavesPocket(p tetris.Piece, x, y int) bool {
+	for _, b := range p.Pos {
+		for _, n := range neighbours(x+b.X, y+b.Y) {
+			if c.empty(n[0], n[1]) {
+				if size := c.region(n[0], n[1]); size <= maxPocket && size%4 != 0 {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// region flood fills the empty cells connected to (x, y) and returns how many
+// there are, stopping once there are more than maxPocket.
+func (c *squareCutter) region(x, y int) int {
+	c.stamp++
+	size := 0
+	stack := append(c.stack[:0], [2]int{x, y})
+	c.seen[y*c.size+x] = c.stamp
+
+	for len(stack) > 0 && size <= maxPocket {
+		cell := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		size++
+
+		for _, n := range neighbours(cell[0], cell[1]) {
+			if c.empty(n[0], n[1]) && c.seen[n[1]*c.size+n[0]] != c.stamp {
+				c.seen[n[1]*c.size+n[0]] = c.stamp
+				stack = append(stack, n)
+			}
+		}
+	}
+
+	c.stack = stack
+	return size
+}
+
+// neighbours returns the cells orthogonally next to (x, y), whether on the square or not.
+func neighbours(x, y int) [4][2]int {
+	return [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}}
+}
+
+// empty reports whether (x, y) is an empty cell of the square.
+func (c *squareCutter) empty(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.size && y < c.size && !c.cells[y][x]
+}
+
+// fits reports whether piece p placed at (x, y) lies on empty cells of the square.
+func (c *squareCutter) fits(p tetris.Piece, x, y int) bool {
+	if x < 0 || y < 0 || x+p.Width > c.size || y+p.Height > c.size {
+		return false
+	}
+
+	return !slices.ContainsFunc(p.Pos, func(b tetris.Point) bool { return c.cells[y+b.Y][x+b.X] })
+}
+
+// set marks the cells of piece p placed at (x, y) as filled or empty.
+func (c *squareCutter) set(p tetris.Piece, x, y int, filled bool) {
+	for _, b := range p.Pos {
+		c.cells[y+b.Y][x+b.X] = filled
+	}
+}
+
+// cutSquare returns pieces that tile a size×size square exactly, in random order.
+//
+// A random search can get stuck below an early mistake that leaves a pocket
+// no piece fits, so each attempt is cut short after a few nodes per cell and
+// the search restarts. An attempt that ends below its limit proves that the
+// shapes cannot tile the square.
+func cutSquare(rng *rand.Rand, size int, weights map[tetris.Shape]int) ([]tetris.Piece, error) {
+	c := &squareCutter{
+		rng:          rng,
+		weights:      weights,
+		size:         size,
+		orientations: map[tetris.Shape][]tetris.Piece{},
+		seen:         make([]int, size*size),
+	}
+
+	for shape := range weights {
+		c.orientations[shape] = shape.Orientations()
+	}
+
+	for c.nodes < maxCutNodes {
+		c.cells = make([][]bool, size)
+		for y := range c.cells {
+			c.cells[y] = make([]bool, size)
+		}
+
+		c.pieces = c.pieces[:0]
+		c.limit = min(c.nodes+restartNodesPerCell*size*size, maxCutNodes)
+
+		if c.cut(0) {
+			rng.Shuffle(len(c.pieces), func(i, j int) {
+				c.pieces[i], c.pieces[j] = c.pieces[j], c.pieces[i]
+			})
+
+			return c.pieces, nil
+		}
+
+		if c.nodes <= c.limit {
+			break
+		}
+	}
+
+	return nil, fmt.Errorf("cannot cut a %d×%d square into the given shapes", size, size)
+}
+
+// formatPieces renders pieces in the text input format, each in the top-left
+// corner of a 4×4 grid.
+func formatPieces(pieces []tetris.Piece) string {
+	var sb strings.Builder
+
+	for i, p := range pieces {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+
+		grid := [4][4]byte{}
+		for y := range grid {
+			grid[y] = [4]byte{'.', '.', '.', '.'}
+		}
+
+		for _, b := range p.Pos {
+			grid[b.Y][b.X] = '#'
+		}
+
+		for _, row := range grid {
+			sb.Write(row[:])
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// runGenerate writes a puzzle in the text input format to w.
+func runGenerate(opts generateOptions, w io.Writer) error {
+	rng := rand.New(rand.NewPCG(opts.seed, 0))
+
+	var pieces []tetris.Piece
+
+	if opts.perfect != 0 {
+		var err error
+		if pieces, err = cutSquare(rng, opts.perfect, opts.weights); err != nil {
+			return err
+		}
+	} else {
+		pieces = randomPieces(rng, opts.pieces, opts.weights)
+	}
+
+	_, err := io.WriteString(w, formatPieces(pieces))
+	return err
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"testing"
+
+	"tetris-optimizer/solver"
+	"tetris-optimizer/tetris"
+)
+
+func TestParseShapeWeights(t *testing.T) {
+	testData := []struct {
+		name     string
+		input    string
+		expected map[tetris.Shape]int
+		wantErr  bool
+	}{
+		{"Empty", "", map[tetris.Shape]int{
+			tetris.ShapeI: 1, tetris.ShapeO: 1, tetris.ShapeT: 1, tetris.ShapeS: 1,
+			tetris.ShapeZ: 1, tetris.ShapeJ: 1, tetris.ShapeL: 1,
+		}, false},
+		{"Weights", "T=3,L=2,O=1", map[tetris.Shape]int{tetris.ShapeT: 3, tetris.ShapeL: 2, tetris.ShapeO: 1}, false},
+		{"Bare letters", "i,s", map[tetris.Shape]int{tetris.ShapeI: 1, tetris.ShapeS: 1}, false},
+		{"Zero weight", "T=0,O=2", map[tetris.Shape]int{tetris.ShapeT: 0, tetris.ShapeO: 2}, false},
+		{"Unknown shape", "X=1", nil, true},
+		{"Negative weight", "T=-1", nil, true},
+		{"Not a number", "T=a", nil, true},
+		{"All zero", "T=0,O=0", nil, true},
+		{"Repeated shape", "T=1,T=0", nil, true},
+		{"Repeated shape in other case", "o,O=2", nil, true},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			weights, err := parseShapeWeights(test.input)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %v, got %v", test.wantErr, err)
+			}
+
+			if len(weights) != len(test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, weights)
+			}
+
+			for shape, weight := range test.expected {
+				if weights[shape] != weight {
+					t.Errorf("expected %v weight %d, got %d", shape, weight, weights[shape])
+				}
+			}
+		})
+	}
+}
+
+func TestParseGenerateArgs(t *testing.T) {
+	testData := []struct {
+		name    string
+		args    []string
+		pieces  int
+		perfect int
+		seeded  bool
+		wantErr bool
+	}{
+		{"Pieces", []string{"--pieces", "5"}, 5, 0, false, false},
+		{"Perfect", []string{"--perfect", "8", "--seed", "0"}, 0, 8, true, false},
+		{"Shapes", []string{"--pieces", "3", "--shapes", "T=1"}, 3, 0, false, false},
+		{"Nothing", []string{}, 0, 0, false, true},
+		{"Both", []string{"--pieces", "5", "--perfect", "8"}, 0, 0, false, true},
+		{"Odd side", []string{"--perfect", "7"}, 0, 0, false, true},
+		{"Side too large", []string{"--perfect", "66"}, 0, 0, false, true},
+		{"Negative pieces", []string{"--pieces", "-1"}, 0, 0, false, true},
+		{"Extra argument", []string{"--pieces", "5", "file.txt"}, 0, 0, false, true},
+		{"Bad shapes", []string{"--pieces", "5", "--shapes", "Q"}, 0, 0, false, true},
+		{"Unknown flag", []string{"--size", "5"}, 0, 0, false, true},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := parseGenerateArgs(test.args)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("expected error %v, got %v", test.wantErr, err)
+			}
+
+			if test.wantErr {
+				return
+			}
+
+			if opts.pieces != test.pieces || opts.perfect != test.perfect || opts.seeded != test.seeded {
+				t.Errorf("expected pieces %d, perfect %d, seeded %v, got %+v",
+					test.pieces, test.perfect, test.seeded, opts)
+			}
+		})
+	}
+}
+
+// generatePuzzle runs the generate command line args and reads the puzzle back.
+func generatePuzzle(t *testing.T, args ...string) (string, []tetris.Piece) {
+	t.Helper()
+
+	opts, err := parseGenerateArgs(args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var sb strings.Builder
+	if err := runGenerate(opts, &sb); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pieces, err := readPieces(strings.NewReader(sb.String()), false, 0, ParseOptions{})
+	if err != nil {
+		t.Fatalf("generated puzzle does not read back: %v", err)
+	}
+
+	return sb.String(), pieces
+}
+
+func TestRunGenerateRandom(t *testing.T) {
+	testData := []struct {
+		name   string
+		shapes string
+		count  int
+	}{
+		{"All shapes", "", 20},
+		{"Only T", "T=1", 10},
+		{"No I", "I=0,O=1,S=1", 30},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			args := []string{"--pieces", strconv.Itoa(test.count), "--seed", "42"}
+			if test.shapes != "" {
+				args = append(args, "--shapes", test.shapes)
+			}
+
+			_, pieces := generatePuzzle(t, args...)
+			if len(pieces) != test.count {
+				t.Fatalf("expected %d pieces, got %d", test.count, len(pieces))
+			}
+
+			weights, _ := parseShapeWeights(test.shapes)
+			for shape, count := range tetris.CountShapes(pieces) {
+				if weights[shape] == 0 {
+					t.Errorf("expected no %v pieces, got %d", shape, count)
+				}
+			}
+		})
+	}
+}
+
+func TestRunGenerateSeed(t *testing.T) {
+	first, _ := generatePuzzle(t, "--perfect", "8", "--seed", "7")
+	second, _ := generatePuzzle(t, "--perfect", "8", "--seed", "7")
+
+	if first != second {
+		t.Errorf("expected the same puzzle from the same seed, got\n%s\nand\n%s", first, second)
+	}
+}
+
+func TestRunGeneratePerfect(t *testing.T) {
+	testData := []struct {
+		name   string
+		side   int
+		shapes string
+	}{
+		{"Square of O", 4, "O=1"},
+		{"All shapes", 6, ""},
+		{"Only T", 4, "T=1"},
+		{"L and J", 4, "L=1,J=1"},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			args := []string{"--perfect", strconv.Itoa(test.side), "--seed", "3"}
+			if test.shapes != "" {
+				args = append(args, "--shapes", test.shapes)
+			}
+
+			_, pieces := generatePuzzle(t, args...)
+
+			board, err := FindSmallestSquare(context.Background(), pieces, &solver.Hybrid{Timeout: solver.DefaultHybridTimeout})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if board.Width != test.side {
+				t.Errorf("expected a %d×%d square, got %d×%d", test.side, test.side, board.Width, board.Height)
+			}
+		})
+	}
+}
+
+func TestCutSquareImpossible(t *testing.T) {
+	testData := []struct {
+		name    string
+		size    int
+		weights map[tetris.Shape]int
+	}{
+		{"I in 6×6", 6, map[tetris.Shape]int{tetris.ShapeI: 1}},
+		{"S and Z", 8, map[tetris.Shape]int{tetris.ShapeS: 1, tetris.ShapeZ: 1}},
+		{"L and J in 6×6", 6, map[tetris.Shape]int{tetris.ShapeL: 1, tetris.ShapeJ: 1}},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := cutSquare(rand.New(rand.NewPCG(1, 0)), test.size, test.weights); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+// Package main contains the JSON input and output formats.
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"tetris-optimizer/tetris"
+)
+
+// JSONInput is the document read by --input-format json.
+type JSONInput struct {
+	Pieces []JSONPiece `json:"pieces"`
+}
+
+// JSONPiece is a piece given as the coordinates of its blocks.
+// Coordinates may start anywhere; the piece is moved to the top-left corner.
+type JSONPiece struct {
+	ID    *int     `json:"id,omitempty"` // Defaults to the index of the piece
+	Cells [][2]int `json:"cells"`        // [x, y] pairs, x to the right and y down
+}
+
+// JSONOutput is the document written by --output-format json.
+type JSONOutput struct {
+	Width   int             `json:"width"`
+	Height  int             `json:"height"`
+	Empty   int             `json:"empty"`             // Cells covered by no piece, blocked cells excluded
+	Blocked [][2]int        `json:"blocked,omitempty"` // Cells outside the container
+	Pieces  []JSONPlacement `json:"pieces"`
+	Stats   JSONStats       `json:"stats"`
+}
+
+// JSONPlacement is where and how a piece was placed on the board.
+type JSONPlacement struct {
+	ID          int      `json:"id"`
+	Label       string   `json:"label,omitempty"` // Text output label, if the alphabet has one
+	Origin      [2]int   `json:"origin"`          // Top-left corner of the bounding box of the orientation
+	Orientation int      `json:"orientation"`     // Index into Piece.Orientations, 0 for the piece as given
+	Cells       [][2]int `json:"cells"`           // Board cells covered by the piece, row by row
+}
+
+// JSONStats describes the search that produced the board.
+type JSONStats struct {
+	Solver    string          `json:"solver"`
+	ElapsedNS int64           `json:"elapsed_ns"`
+	Shapes    map[string]int  `json:"shapes,omitempty"` // Number of pieces of each tetromino shape, or "other"
+	Sizes     []JSONSizeStats `json:"sizes,omitempty"`  // Square sizes settled, in increasing order
+}
+
+// JSONSizeStats describes the search of one square size, as reported by --stats.
+type JSONSizeStats struct {
+	Size       int    `json:"size"`
+	Nodes      int64  `json:"nodes"`
+	Placements int64  `json:"placements"`
+	Backtracks int64  `json:"backtracks"`
+	Strategy   string `json:"strategy,omitempty"` // Strategy that placed every piece, empty if none did
+	ElapsedNS  int64  `json:"elapsed_ns"`
+}
+
+// readJSONPieces decodes and validates pieces in the JSON input format.
+// Unless polyomino is set every piece must be a tetromino. A positive limit
+// caps the IDs, e.g. to the size of the label alphabet.
+func readJSONPieces(r io.Reader, polyomino bool, limit int) ([]tetris.Piece, error) {
+	var input JSONInput
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&input); err != nil {
+		return nil, fmt.Errorf("invalid JSON input; %w", err)
+	}
+
+	pieces := make([]tetris.Piece, 0, len(input.Pieces))
+	seen := map[int]bool{}
+
+	for i, jp := range input.Pieces {
+		id := i
+		if jp.ID != nil {
+			id = *jp.ID
+		}
+
+		if id < 0 || (limit > 0 && id >= limit) {
+			return nil, fmt.Errorf("piece %d: ID %d is out of range", i, id)
+		}
+
+		if seen[id] {
+			return nil, fmt.Errorf("piece %d: ID %d is used more than once", i, id)
+		}
+
+		seen[id] = true
+
+		if !polyomino && len(jp.Cells) != 4 {
+			return nil, fmt.Errorf("piece %d: %w", i, &tetris.BlockCountError{Kind: tetris.KindTetromino, Count: len(jp.Cells)})
+		}
+
+		raw, err := cellGrid(jp.Cells)
+		if err != nil {
+			return nil, fmt.Errorf("piece %d: %w", i, err)
+		}
+
+		p, err := tetris.InitPolyomino(raw, id)
+		if err != nil {
+			return nil, fmt.Errorf("piece %d: %w", i, err)
+		}
+
+		pieces = append(pieces, p)
+	}
+
+	return pieces, nil
+}
+
+// cellGrid draws coordinate pairs as a '#'/'.' grid spanning their bounding box.
+func cellGrid(cells [][2]int) (tetris.RawPiece, error) {
+	if len(cells) == 0 {
+		return nil, &tetris.BlockCountError{Kind: tetris.KindPolyomino}
+	}
+
+	minX, minY, maxX, maxY := cells[0][0], cells[0][1], cells[0][0], cells[0][1]
+
+	for _, c := range cells {
+		minX, minY = min(minX, c[0]), min(minY, c[1])
+		maxX, maxY = max(maxX, c[0]), max(maxY, c[1])
+	}
+
+	// The spans are taken as unsigned, where they cannot overflow for any coordinates.
+	if uint(maxX)-uint(minX) >= tetris.MaxSize || uint(maxY)-uint(minY) >= tetris.MaxSize {
+		return nil, fmt.Errorf("piece should be at most %d cells wide and tall", tetris.MaxSize)
+	}
+
+	raw := make(tetris.RawPiece, maxY-minY+1)
+	for y := range raw {
+		raw[y] = bytes.Repeat([]byte{'.'}, maxX-minX+1)
+	}
+
+	for _, c := range cells {
+		if raw[c[1]-minY][c[0]-minX] == '#' {
+			return nil, fmt.Errorf("cell [%d, %d] is repeated", c[0], c[1])
+		}
+
+		raw[c[1]-minY][c[0]-minX] = '#'
+	}
+
+	return raw, nil
+}
+
+// EncodePieces returns pieces in the JSON input format, with explicit IDs.
+func EncodePieces(pieces []tetris.Piece) JSONInput {
+	input := JSONInput{Pieces: make([]JSONPiece, len(pieces))}
+
+	for i, p := range pieces {
+		id := p.ID
+		input.Pieces[i] = JSONPiece{ID: &id, Cells: pointPairs(p.Pos)}
+	}
+
+	return input
+}
+
+// pointPairs converts points to [x, y] pairs.
+func pointPairs(points []tetris.Point) [][2]int {
+	pairs := make([][2]int, len(points))
+
+	for i, p := range points {
+		pairs[i] = [2]int{p.X, p.Y}
+	}
+
+	return pairs
+}
+
+// NewJSONOutput describes a solved board in the JSON output format.
+// Pieces missing from the board, as in partial results, are left out.
+func NewJSONOutput(board tetris.Board, pieces []tetris.Piece, alphabet string, stats JSONStats) JSONOutput {
+	labels := []rune(alphabet)
+	cells := board.Cells()
+	out := JSONOutput{
+		Width:  board.Width,
+		Height: board.Height,
+		Empty:  board.Free() - board.Filled(),
+		Pieces: []JSONPlacement{},
+		Stats:  stats,
+	}
+
+	for y := range board.Height {
+		for x := range board.Width {
+			if board.Blocked(x, y) {
+				out.Blocked = append(out.Blocked, [2]int{x, y})
+			}
+		}
+	}
+
+	for _, p := range pieces {
+		placed, ok := cells[p.ID]
+		if !ok {
+			continue
+		}
+
+		placement := JSONPlacement{ID: p.ID, Orientation: -1, Cells: pointPairs(placed)}
+		if p.ID < len(labels) {
+			placement.Label = string(labels[p.ID])
+		}
+
+		shape := tetris.NormalizedBlocks(placed)
+		placement.Origin = [2]int{placed[0].X - shape[0].X, placed[0].Y - shape[0].Y}
+
+		for i, o := range p.Orientations() {
+			if slices.Equal(tetris.NormalizedBlocks(o.Pos), shape) {
+				placement.Orientation = i
+				break
+			}
+		}
+
+		out.Pieces = append(out.Pieces, placement)
+	}
+
+	slices.SortFunc(out.Pieces, func(a, b JSONPlacement) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return out
+}
+
+// formatJSON renders the board and the search statistics as a single-line JSON document.
+func formatJSON(board tetris.Board, pieces []tetris.Piece, opts options, stats Stats) string {
+	out := JSONStats{Solver: opts.solver, ElapsedNS: stats.Elapsed.Nanoseconds(), Shapes: map[string]int{}}
+	for shape, count := range tetris.CountShapes(pieces) {
+		out.Shapes[shapeName(shape)] = count
+	}
+
+	for _, s := range stats.Sizes {
+		out.Sizes = append(out.Sizes, JSONSizeStats{
+			Size:       s.Size,
+			Nodes:      s.Nodes,
+			Placements: s.Placements,
+			Backtracks: s.Backtracks,
+			Strategy:   s.Strategy,
+			ElapsedNS:  s.Elapsed.Nanoseconds(),
+		})
+	}
+
+	data, _ := json.Marshal(NewJSONOutput(board, pieces, opts.alphabet, out))
+
+	return string(data) + "\n"
+}
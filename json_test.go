@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"tetris-optimizer/solver"
+	"tetris-optimizer/tetris"
+)
+
+func TestReadJSONPieces(t *testing.T) {
+	testData := []struct {
+		name        string
+		input       string
+		polyomino   bool
+		expectedIDs []int
+		errMsg      string
+	}{
+		{"Default IDs", `{"pieces": [{"cells": [[0,0],[1,0],[2,0],[3,0]]}, {"cells": [[0,0],[1,0],[0,1],[1,1]]}]}`, false, []int{0, 1}, ""},
+		{"Explicit IDs", `{"pieces": [{"id": 4, "cells": [[0,0],[1,0],[2,0],[3,0]]}, {"cells": [[0,0],[1,0],[0,1],[1,1]]}]}`, false, []int{4, 1}, ""},
+		{"Offset cells", `{"pieces": [{"cells": [[-3,7],[-3,8],[-2,8],[-2,9]]}]}`, false, []int{0}, ""},
+		{"Polyomino", `{"pieces": [{"cells": [[0,0],[1,0],[2,0],[3,0],[4,0]]}]}`, true, []int{0}, ""},
+		{"Empty list", `{"pieces": []}`, false, []int{}, ""},
+		{"Malformed", `{"pieces": [`, false, nil, "invalid JSON input; unexpected EOF"},
+		{"Unknown field", `{"pieces": [{"shape": []}]}`, false, nil, `invalid JSON input; json: unknown field "shape"`},
+		{"Negative ID", `{"pieces": [{"id": -1, "cells": [[0,0],[1,0],[2,0],[3,0]]}]}`, false, nil, "piece 0: ID -1 is out of range"},
+		{"ID beyond labels", `{"pieces": [{"id": 62, "cells": [[0,0],[1,0],[2,0],[3,0]]}]}`, false, nil, "piece 0: ID 62 is out of range"},
+		{
+			"Repeated ID", `{"pieces": [{"cells": [[0,0],[1,0],[2,0],[3,0]]}, {"id": 0, "cells": [[0,0],[1,0],[2,0],[3,0]]}]}`,
+			false, nil, "piece 1: ID 0 is used more than once",
+		},
+		{"Not a tetromino", `{"pieces": [{"cells": [[0,0],[1,0],[2,0]]}]}`, false, nil, "piece 0: tetromino should have 4 blocks"},
+		{"No cells", `{"pieces": [{"cells": []}]}`, true, nil, "piece 0: polyomino should have at least 1 block"},
+		{"Repeated cell", `{"pieces": [{"cells": [[0,0],[1,0],[1,0],[2,0]]}]}`, false, nil, "piece 0: cell [1, 0] is repeated"},
+		{"Disconnected", `{"pieces": [{"cells": [[0,0],[1,0],[3,0],[4,0]]}]}`, false, nil, "piece 0: invalid polyomino"},
+		{"Too wide", `{"pieces": [{"cells": [[0,0],[64,0]]}]}`, true, nil, "piece 0: piece should be at most 64 cells wide and tall"},
+		{
+			"Extreme coordinates", `{"pieces":[{"cells":[[-9223372036854775808,0],[9223372036854775807,0],[0,0],[1,0]]}]}`,
+			true, nil, "piece 0: piece should be at most 64 cells wide and tall",
+		},
+		{
+			"Extreme rows", `{"pieces":[{"cells":[[0,-9223372036854775808],[0,9223372036854775807]]}]}`,
+			true, nil, "piece 0: piece should be at most 64 cells wide and tall",
+		},
+		{"Far from the origin", `{"pieces":[{"cells":[[9223372036854775806,0],[9223372036854775807,0]]}]}`, true, []int{0}, ""},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			pieces, err := readJSONPieces(strings.NewReader(test.input), test.polyomino, len(tetris.DefaultAlphabet))
+
+			if test.errMsg != "" {
+				if err == nil || err.Error() != test.errMsg {
+					t.Fatalf("expected error %q, got %v", test.errMsg, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids := []int{}
+			for _, p := range pieces {
+				ids = append(ids, p.ID)
+
+				if p.Pos[0].Y != 0 || slices.ContainsFunc(p.Pos, func(q tetris.Point) bool { return q.X < 0 || q.Y < 0 }) {
+					t.Errorf("expected piece %d to be normalized, got %v", p.ID, p.Pos)
+				}
+			}
+
+			if !slices.Equal(ids, test.expectedIDs) {
+				t.Errorf("expected IDs %v, got %v", test.expectedIDs, ids)
+			}
+		})
+	}
+}
+
+func TestJSONPiecesRoundTrip(t *testing.T) {
+	for _, file := range []string{"tests/samples/sample00-04", "tests/good_examples/goodexample03-05"} {
+		t.Run(file, func(t *testing.T) {
+			pieces := loadPieces(t, file)
+
+			data, err := json.Marshal(EncodePieces(pieces))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			decoded, err := readJSONPieces(strings.NewReader(string(data)), false, 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(decoded, pieces) {
+				t.Fatalf("expected %+v, got %+v", pieces, decoded)
+			}
+		})
+	}
+}
+
+func TestJSONOutputRoundTrip(t *testing.T) {
+	testData := []struct {
+		name       string
+		file       string
+		transforms tetris.Transform
+		container  string
+	}{
+		{"Square", "tests/samples/sample00-04", 0, ""},
+		{"Rotated", "tests/samples/sample00-04", tetris.Rotate | tetris.Mirror, ""},
+		{"Container", "tests/good_examples/goodexample01-09", tetris.Rotate, "tests/containers/cross"},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			pieces := withTransforms(loadPieces(t, test.file), test.transforms)
+			s, _ := solver.New(solver.DefaultName)
+
+			var (
+				board tetris.Board
+				stats Stats
+				err   error
+			)
+
+			if test.container != "" {
+				container, readErr := readContainer(test.container)
+				if readErr != nil {
+					t.Fatalf("unexpected error: %v", readErr)
+				}
+
+				board, err = FitContainer(context.Background(), pieces, container, s)
+			} else {
+				board, stats, err = FindSmallestSquareStats(context.Background(), pieces, s)
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			opts := defaultOptions(func(o *options) { o.outputFormat = "json" })
+			stats.Elapsed = time.Millisecond
+			text := formatBoard(board, pieces, opts, stats)
+
+			var out JSONOutput
+			if err := json.Unmarshal([]byte(text), &out); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if out.Stats.Solver != solver.DefaultName || out.Stats.ElapsedNS != 1e6 {
+				t.Errorf("unexpected stats %+v", out.Stats)
+			}
+
+			if shapes := tetris.CountShapes(pieces); len(out.Stats.Shapes) != len(shapes) {
+				t.Errorf("expected %d shapes, got %v", len(shapes), out.Stats.Shapes)
+			}
+
+			if len(out.Stats.Sizes) != len(stats.Sizes) {
+				t.Fatalf("expected %d sizes, got %+v", len(stats.Sizes), out.Stats.Sizes)
+			}
+
+			for i, s := range stats.Sizes {
+				expected := JSONSizeStats{
+					Size: s.Size, Nodes: s.Nodes, Placements: s.Placements, Backtracks: s.Backtracks,
+					Strategy: s.Strategy, ElapsedNS: s.Elapsed.Nanoseconds(),
+				}
+
+				if out.Stats.Sizes[i] != expected {
+					t.Errorf("size %d: expected %+v, got %+v", i, expected, out.Stats.Sizes[i])
+				}
+			}
+
+			if n := len(out.Stats.Sizes); test.container == "" && (n == 0 || out.Stats.Sizes[n-1].Size != board.Width || out.Stats.Sizes[n-1].Strategy == "") {
+				t.Errorf("expected the last size to be solved at %d, got %+v", board.Width, out.Stats.Sizes)
+			}
+
+			if out.Empty != board.Free()-board.Filled() || len(out.Pieces) != len(pieces) {
+				t.Fatalf("expected %d pieces and %d empty cells, got %+v", len(pieces), board.Free()-board.Filled(), out)
+			}
+
+			// Rebuild the board from the origin and orientation of each placement.
+			rebuilt := tetris.NewRectBoard(uint(out.Width), uint(out.Height))
+			for _, cell := range out.Blocked {
+				rebuilt.Block(cell[0], cell[1])
+			}
+
+			for i, placement := range out.Pieces {
+				if placement.ID != pieces[i].ID || placement.Label != string(tetris.DefaultAlphabet[i]) {
+					t.Fatalf("expected piece %d labelled %c, got %+v", i, tetris.DefaultAlphabet[i], placement)
+				}
+
+				o := pieces[i].Orientations()[placement.Orientation]
+				rebuilt.Place(o, placement.Origin[0], placement.Origin[1])
+
+				if !slices.Equal(placement.Cells, pointPairs(rebuilt.Cells()[placement.ID])) {
+					t.Fatalf("expected cells %v for piece %d, got %v", rebuilt.Cells()[placement.ID], i, placement.Cells)
+				}
+			}
+
+			if !rebuilt.Equal(board) {
+				t.Fatalf("expected:\n%s\ngot:\n%s", board.ToString(), rebuilt.ToString())
+			}
+		})
+	}
+}
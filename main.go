@@ -3,61 +3,539 @@ package main
 
 import (
 	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"tetris-optimizer/solver"
 	"tetris-optimizer/tetris"
 )
 
-// initTetrominoPieces converts raw tetrominoes to validated pieces with IDs A-Z.
-func initTetrominoPieces(rawTetrominoes []tetris.RawPiece) ([]tetris.Piece, error) {
-	idLimit := int('Z'-'A') + 1
+// initTetrominoPieces converts raw tetrominoes to validated pieces with IDs 0, 1, 2, ...
+// A positive limit caps the number of pieces, e.g. to the size of the label alphabet.
+func initTetrominoPieces(rawTetrominoes []tetris.RawPiece, limit int) ([]tetris.Piece, error) {
+	return initPieces(rawTetrominoes, tetris.Init, "tetrominoes", limit)
+}
+
+// initPolyominoPieces converts raw polyominoes to validated pieces with IDs 0, 1, 2, ...
+// A positive limit caps the number of pieces, e.g. to the size of the label alphabet.
+func initPolyominoPieces(rawPolyominoes []tetris.RawPiece, limit int) ([]tetris.Piece, error) {
+	return initPieces(rawPolyominoes, tetris.InitPolyomino, "polyominoes", limit)
+}
 
-	if len(rawTetrominoes) > idLimit {
-		return nil, fmt.Errorf("cannot process more than %d tetrominoes", idLimit)
+// initPieces validates raw pieces with init and assigns IDs in input order.
+// kind names the pieces in the error for inputs that exceed the limit.
+func initPieces(
+	raws []tetris.RawPiece, init func(tetris.RawPiece, int) (tetris.Piece, error), kind string, limit int,
+) ([]tetris.Piece, error) {
+	if err := checkPieceCount(len(raws), limit, kind); err != nil {
+		return nil, err
 	}
 
 	var pieces []tetris.Piece
-	id := byte('A')
 
-	for _, raw := range rawTetrominoes {
-		p, err := tetris.Init(raw, id)
+	for id, raw := range raws {
+		p, err := init(raw, id)
 		if err != nil {
 			return nil, err
 		}
 
 		pieces = append(pieces, p)
-		id++
 	}
 
 	return pieces, nil
 }
 
+// checkPieceCount reports an error if there are more than a positive limit of n pieces.
+// kind names the pieces in the error.
+func checkPieceCount(n, limit int, kind string) error {
+	if limit > 0 && n > limit {
+		return fmt.Errorf("cannot process more than %d %s", limit, kind)
+	}
+
+	return nil
+}
+
+// options holds the parsed command line.
+type options struct {
+	solver       string
+	timeout      time.Duration // Zero disables the timeout
+	parallel     bool
+	parallelOpts ParallelOptions
+	transforms   tetris.Transform // Symmetries every piece may be placed under
+	polyomino    bool             // Accept pieces of any size instead of 4×4 tetrominoes
+	parse        ParseOptions     // How text input is parsed
+	alphabet     string           // Labels of the pieces in text output
+	inputFormat  string           // "text" for '#'/'.' grids, "json" for coordinate lists
+	outputFormat string           // "text" for labelled grids, "numeric" for numeric IDs, "json" for placements
+	container    string           // Shape file to pack into instead of searching squares
+	rectangle    bool             // Search for the smallest-area rectangle instead of square
+	maxAspect    float64          // Longest to shortest side ratio in rectangle mode, 0 for no limit
+	certificate  string           // File to write the optimality certificate of the square to
+	verbose      bool             // Describe the pieces on stderr before searching
+	stats        bool             // Report the search statistics of every size on stderr
+	path         string
+}
+
+// parseArgs parses the command line arguments (without the program name).
+func parseArgs(args []string) (options, error) {
+	var opts options
+
+	flags := flag.NewFlagSet("tetris-optimizer", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.StringVar(&opts.solver, "solver", solver.DefaultName, "search strategy name")
+	flags.DurationVar(&opts.timeout, "timeout", 0, "give up after this long, 0 for no limit")
+	flags.BoolVar(&opts.parallel, "parallel", false, "search root branches on all CPUs")
+	flags.IntVar(&opts.parallelOpts.Sizes, "sizes", 1, "board sizes searched at once in parallel mode")
+	flags.BoolVar(&opts.parallelOpts.Deterministic, "deterministic", false,
+		"in parallel mode, return the same board as the sequential search")
+	flags.BoolVar(&opts.polyomino, "polyomino", false, "accept polyominoes of any size")
+	rotate := flags.Bool("rotate", false, "allow pieces to be rotated")
+	mirror := flags.Bool("mirror", false, "allow pieces to be mirrored")
+	flags.StringVar(&opts.alphabet, "alphabet", tetris.DefaultAlphabet, "piece labels in text output")
+	flags.StringVar(&opts.inputFormat, "input-format", "text", "input format: text or json")
+	flags.BoolVar(&opts.parse.CollectAll, "all-errors", false, "report every error in a text input file")
+	flags.BoolVar(&opts.parse.Lenient, "lenient", false, "accept any line ending, trailing whitespace and custom glyphs")
+	filled := flags.String("filled", "#", "in lenient mode, the glyph of a block")
+	empty := flags.String("empty", ".", "in lenient mode, the glyph of an empty cell")
+	flags.StringVar(&opts.outputFormat, "output-format", "text", "output format: text, numeric or json")
+	flags.StringVar(&opts.container, "container", "", "fit the pieces into the shape in this file")
+	flags.BoolVar(&opts.rectangle, "rectangle", false, "search for the smallest-area rectangle")
+	flags.Float64Var(&opts.maxAspect, "max-aspect", 0, "in rectangle mode, the largest side ratio, 0 for no limit")
+	flags.StringVar(&opts.certificate, "certificate", "", "write a proof that the square is the smallest to this file")
+	flags.BoolVar(&opts.verbose, "verbose", false, "describe the pieces on stderr")
+	flags.BoolVar(&opts.stats, "stats", false, "report search statistics on stderr")
+
+	if err := flags.Parse(args); err != nil {
+		return opts, err
+	}
+
+	if *rotate {
+		opts.transforms |= tetris.Rotate
+	}
+
+	if *mirror {
+		opts.transforms |= tetris.Mirror
+	}
+
+	if flags.NArg() != 1 {
+		return opts, errors.New("expected exactly one tetromino file")
+	}
+
+	if opts.timeout < 0 {
+		return opts, errors.New("timeout should not be negative")
+	}
+
+	if opts.maxAspect != 0 && opts.maxAspect < 1 {
+		return opts, errors.New("max aspect should be 0 or at least 1")
+	}
+
+	if opts.maxAspect != 0 && !opts.rectangle {
+		return opts, errors.New("max aspect requires rectangle mode")
+	}
+
+	if opts.rectangle && opts.container != "" {
+		return opts, errors.New("rectangle and container modes cannot be combined")
+	}
+
+	if opts.certificate != "" && (opts.rectangle || opts.container != "") {
+		return opts, errors.New("certificates are only available for the square search")
+	}
+
+	if opts.stats && (opts.rectangle || opts.container != "") {
+		return opts, errors.New("stats are only available for the square search")
+	}
+
+	if opts.parallelOpts.Sizes < 1 {
+		return opts, errors.New("sizes should be at least 1")
+	}
+
+	if !slices.Contains(solver.Names(), opts.solver) {
+		return opts, fmt.Errorf("unknown solver %q", opts.solver)
+	}
+
+	if !slices.Contains([]string{"text", "json"}, opts.inputFormat) {
+		return opts, fmt.Errorf("unknown input format %q", opts.inputFormat)
+	}
+
+	if !slices.Contains([]string{"text", "numeric", "json"}, opts.outputFormat) {
+		return opts, fmt.Errorf("unknown output format %q", opts.outputFormat)
+	}
+
+	if _, err := tetris.Labels(opts.alphabet); err != nil {
+		return opts, err
+	}
+
+	if utf8.RuneCountInString(*filled) != 1 || utf8.RuneCountInString(*empty) != 1 {
+		return opts, errors.New("glyphs should be single characters")
+	}
+
+	opts.parse.Filled, _ = utf8.DecodeRuneInString(*filled)
+	opts.parse.Empty, _ = utf8.DecodeRuneInString(*empty)
+
+	if (opts.parse.Filled != '#' || opts.parse.Empty != '.') && !opts.parse.Lenient {
+		return opts, errors.New("custom glyphs require lenient mode")
+	}
+
+	if err := opts.parse.validate(); err != nil {
+		return opts, err
+	}
+
+	opts.path = flags.Arg(0)
+	return opts, nil
+}
+
+// pieceLimit returns how many pieces the output format can label, 0 for no limit.
+func pieceLimit(opts options) int {
+	if opts.outputFormat != "text" {
+		return 0
+	}
+
+	return len([]rune(opts.alphabet))
+}
+
+// formatBoard renders the board in the output format selected by opts.
+// The pieces and the search statistics are only used by the JSON format.
+func formatBoard(board tetris.Board, pieces []tetris.Piece, opts options, stats Stats) string {
+	switch opts.outputFormat {
+	case "numeric":
+		return board.FormatNumeric()
+	case "json":
+		return formatJSON(board, pieces, opts, stats)
+	}
+
+	return board.Format(opts.alphabet)
+}
+
+// shapeName returns the letter of a shape, or "other" for pieces that are not tetrominoes.
+func shapeName(shape tetris.Shape) string {
+	if shape == tetris.NoShape {
+		return "other"
+	}
+
+	return shape.String()
+}
+
+// shapeSummary lists how many pieces there are of each shape, most common first,
+// e.g. "3×T, 2×L, 1×O".
+func shapeSummary(pieces []tetris.Piece) string {
+	counts := tetris.CountShapes(pieces)
+	shapes := slices.Collect(maps.Keys(counts))
+
+	// Pieces that are not tetrominoes come last, whatever their number.
+	other := func(shape tetris.Shape) int {
+		if shape == tetris.NoShape {
+			return 1
+		}
+
+		return 0
+	}
+
+	slices.SortFunc(shapes, func(a, b tetris.Shape) int {
+		return cmp.Or(cmp.Compare(other(a), other(b)), cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+
+	parts := make([]string, len(shapes))
+	for i, shape := range shapes {
+		parts[i] = fmt.Sprintf("%d×%s", counts[shape], shapeName(shape))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// readPieces parses and validates tetrominoes, or polyominoes of any size.
+// A positive limit caps the number of pieces. Pieces that do not validate are
+// reported as *ParseError like format errors, and collected with them if
+// parse.CollectAll is set.
+func readPieces(r io.Reader, polyomino bool, limit int, parse ParseOptions) ([]tetris.Piece, error) {
+	p, err := newLineParser(r, parse)
+	if err != nil {
+		return nil, err
+	}
+
+	if polyomino {
+		raws := p.polyominoes()
+		if err := checkPieceCount(len(raws), limit, "polyominoes"); err != nil && len(p.errs) == 0 {
+			return nil, err
+		}
+
+		return p.validate(raws, tetris.InitPolyomino)
+	}
+
+	raws := p.tetrominoes()
+	if err := checkPieceCount(len(raws), limit, "tetrominoes"); err != nil && len(p.errs) == 0 {
+		return nil, err
+	}
+
+	return p.validate(raws, tetris.Init)
+}
+
+// newSolver returns a fresh instance of the solver selected by opts.
+func newSolver(opts options) solver.Solver {
+	s, _ := solver.New(opts.solver) // The name was validated by parseArgs.
+	return s
+}
+
+// findSmallestSquare runs the sequential or parallel search selected by opts.
+func findSmallestSquare(ctx context.Context, tetrominoes []tetris.Piece, opts options) (tetris.Board, Stats, error) {
+	if opts.parallel {
+		factory := func() solver.Solver { return newSolver(opts) }
+		return FindSmallestSquareParallelStats(ctx, tetrominoes, factory, opts.parallelOpts)
+	}
+
+	return FindSmallestSquareStats(ctx, tetrominoes, newSolver(opts))
+}
+
+// readContainer parses the container shape file at path.
+func readContainer(path string) (tetris.Board, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return tetris.Board{}, err
+	}
+
+	defer file.Close()
+	return ParseContainer(bufio.NewScanner(file))
+}
+
+// boardSolver returns the solver for a single board, with its root branches
+// searched concurrently in parallel mode.
+func boardSolver(opts options) solver.Solver {
+	s := newSolver(opts)
+
+	if opts.parallel {
+		s = solver.Parallel{
+			Inner:         s,
+			Workers:       opts.parallelOpts.Workers,
+			Deterministic: opts.parallelOpts.Deterministic,
+		}
+	}
+
+	return s
+}
+
+// fitContainer packs the pieces into the container with the sequential or parallel search selected by opts.
+func fitContainer(
+	ctx context.Context, tetrominoes []tetris.Piece, container tetris.Board, opts options,
+) (tetris.Board, error) {
+	return FitContainer(ctx, tetrominoes, container, boardSolver(opts))
+}
+
+// findSmallestRectangle runs the rectangle search with the sequential or parallel search selected by opts.
+func findSmallestRectangle(ctx context.Context, tetrominoes []tetris.Piece, opts options) (tetris.Board, error) {
+	return FindSmallestRectangle(ctx, tetrominoes, boardSolver(opts), opts.maxAspect)
+}
+
+// writeCertificate proves that the pieces do not fit in a square smaller than size
+// and writes the certificate to path.
+func writeCertificate(ctx context.Context, tetrominoes []tetris.Piece, size int, path string) error {
+	cert, err := Certify(ctx, tetrominoes, size)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteCertificate(file, cert); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// verify runs the verify subcommand.
+func verify() {
+	opts, err := parseVerifyArgs(os.Args[2:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		fmt.Fprintf(os.Stderr, "ERROR: USAGE: %s verify [--polyomino] [--rotate] [--mirror] [--alphabet labels] "+
+			"tetromino_file solution_file [certificate_file]\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	if err := runVerify(opts, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generate runs the generate subcommand, reporting the seed on stderr unless it was given.
+func generate() {
+	opts, err := parseGenerateArgs(os.Args[2:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		fmt.Fprintf(os.Stderr, "ERROR: USAGE: %s generate [--seed n] [--shapes I=1,O=1,...] "+
+			"(--pieces n | --perfect side)\n", os.Args[0])
+		os.Exit(1)
+	}
+
+	if !opts.seeded {
+		opts.seed = uint64(time.Now().UnixNano())
+		fmt.Fprintf(os.Stderr, "Seed: %d\n", opts.seed)
+	}
+
+	if err := runGenerate(opts, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// bench runs the bench subcommand.
+func bench() {
+	opts, err := parseBenchArgs(os.Args[2:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		fmt.Fprintf(os.Stderr, "ERROR: USAGE: %s bench [--solver %s] [--runs n] "+
+			"[--record baseline_file | --compare baseline_file [--threshold fraction]]\n",
+			os.Args[0], strings.Join(solver.Names(), "|"))
+		os.Exit(1)
+	}
+
+	if err := runBenchCommand(opts, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 // main parses input file, validates tetrominoes, and prints the solution.
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Fprintf(os.Stderr, "ERROR: USAGE: %s tetromino_file\n", os.Args[0])
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		verify()
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		generate()
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		bench()
+		return
+	}
+
+	opts, err := parseArgs(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		fmt.Fprintf(os.Stderr, "ERROR: USAGE: %s [--solver %s] [--timeout duration] "+
+			"[--parallel [--sizes n] [--deterministic]] [--rotate] [--mirror] [--polyomino] "+
+			"[--alphabet labels] [--input-format text|json] [--all-errors] [--lenient [--filled c] [--empty c]] [--output-format text|numeric|json] "+
+			"[--container shape_file | --rectangle [--max-aspect ratio] | --certificate file] [--verbose] [--stats] tetromino_file\n",
+			os.Args[0], strings.Join(solver.Names(), "|"))
 		os.Exit(1)
 	}
 
-	file, err := os.Open(os.Args[1])
+	file, err := os.Open(opts.path)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
 		os.Exit(1)
 	}
 
 	defer file.Close()
-	rawTetrominoes, err := ParseTetrominoStream(bufio.NewScanner(file))
+
+	var tetrominoes []tetris.Piece
+
+	if opts.inputFormat == "json" {
+		tetrominoes, err = readJSONPieces(file, opts.polyomino, pieceLimit(opts))
+	} else {
+		tetrominoes, err = readPieces(file, opts.polyomino, pieceLimit(opts), opts.parse)
+	}
+
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		// Report each error of a multi-error parse on its own line.
+		var errs ParseErrors
+		if errors.As(err, &errs) {
+			for _, e := range errs {
+				fmt.Fprintf(os.Stderr, "ERROR: %v\n", e)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		}
+
 		os.Exit(1)
 	}
 
-	tetrominoes, err := initTetrominoPieces(rawTetrominoes)
+	for i := range tetrominoes {
+		tetrominoes[i].Transforms = opts.transforms
+	}
+
+	if opts.verbose {
+		fmt.Fprintf(os.Stderr, "Pieces: %d (%s)\n", len(tetrominoes), shapeSummary(tetrominoes))
+	}
+
+	var container tetris.Board
+
+	if opts.container != "" {
+		if container, err = readContainer(opts.container); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Stop cleanly on Ctrl-C so the partial result can still be reported.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	var (
+		board tetris.Board
+		stats Stats
+	)
+
+	start := time.Now()
+
+	switch {
+	case opts.container != "":
+		board, err = fitContainer(ctx, tetrominoes, container, opts)
+	case opts.rectangle:
+		board, err = findSmallestRectangle(ctx, tetrominoes, opts)
+	default:
+		board, stats, err = findSmallestSquare(ctx, tetrominoes, opts)
+	}
+
+	// Container and rectangle searches report no sizes, only the time they took.
+	stats.Elapsed = time.Since(start)
+
+	if opts.stats {
+		fmt.Fprint(os.Stderr, formatStats(stats, opts.solver))
+	}
+
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+
+		if errors.Is(err, ErrInterrupted) {
+			fmt.Fprintf(os.Stderr, "Best partial result:\n%s", formatBoard(board, tetrominoes, opts, stats))
+		}
+
 		os.Exit(1)
 	}
 
-	fmt.Print(FindSmallestSquare(tetrominoes).ToString())
+	// Print the board before certifying it, so that a certificate cut short by
+	// --timeout or Ctrl-C does not discard the optimal board already found.
+	fmt.Print(formatBoard(board, tetrominoes, opts, stats))
+
+	if opts.certificate != "" {
+		if err := writeCertificate(ctx, tetrominoes, board.Width, opts.certificate); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: no certificate written; %v\n", err)
+			os.Exit(1)
+		}
+	}
 }
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"tetris-optimizer/solver"
 	"tetris-optimizer/tetris"
 )
 
@@ -18,35 +22,43 @@ func makeRaws(raw tetris.RawPiece, count int) []tetris.RawPiece {
 
 func TestInitTetrominoPieces(t *testing.T) {
 	// Simple I-piece
-	var rawI tetris.RawPiece
+	rawI := make(tetris.RawPiece, 4)
 
-	for i := range 4 {
-		copy(rawI[i][:], []byte("#..."))
+	for i := range rawI {
+		rawI[i] = []byte("#...")
 	}
 
 	t.Run("At limit", func(t *testing.T) {
-		pieces, err := initTetrominoPieces(makeRaws(rawI, 26))
+		pieces, err := initTetrominoPieces(makeRaws(rawI, 62), 62)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 
-		if len(pieces) != 26 {
-			t.Fatalf("expected 26 pieces, got %d", len(pieces))
+		if len(pieces) != 62 {
+			t.Fatalf("expected 62 pieces, got %d", len(pieces))
 		}
 
-		id := byte('A')
-		for _, p := range pieces {
+		for id, p := range pieces {
 			if p.ID != id {
-				t.Fatalf("expected ID: %c, got: %c", id, p.ID)
+				t.Fatalf("expected ID: %d, got: %d", id, p.ID)
 			}
+		}
+	})
 
-			id++
+	t.Run("No limit", func(t *testing.T) {
+		pieces, err := initTetrominoPieces(makeRaws(rawI, 100), 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(pieces) != 100 || pieces[99].ID != 99 {
+			t.Fatalf("expected 100 pieces with IDs up to 99, got %d", len(pieces))
 		}
 	})
 
 	t.Run("More than max limit", func(t *testing.T) {
-		expectedMsg := "cannot process more than 26 tetrominoes"
-		_, err := initTetrominoPieces(makeRaws(rawI, 27))
+		expectedMsg := "cannot process more than 62 tetrominoes"
+		_, err := initTetrominoPieces(makeRaws(rawI, 63), 62)
 		if err == nil {
 			t.Fatal("expected error but got nil")
 		}
@@ -56,3 +68,255 @@ func TestInitTetrominoPieces(t *testing.T) {
 		}
 	})
 }
+
+// defaultOptions returns the options parsed from a bare "file" argument, adjusted by edit.
+func defaultOptions(edit func(*options)) options {
+	opts := options{
+		solver:       solver.DefaultName,
+		parallelOpts: ParallelOptions{Sizes: 1},
+		alphabet:     tetris.DefaultAlphabet,
+		inputFormat:  "text",
+		parse:        ParseOptions{Filled: '#', Empty: '.'},
+		outputFormat: "text",
+		path:         "file",
+	}
+
+	if edit != nil {
+		edit(&opts)
+	}
+
+	return opts
+}
+
+func TestParseArgs(t *testing.T) {
+	testData := []struct {
+		name        string
+		args        []string
+		expected    options
+		expectError bool
+	}{
+		{"Path only", []string{"file"}, defaultOptions(nil), false},
+		{
+			"DLX solver", []string{"--solver", "dlx", "file"},
+			defaultOptions(func(o *options) { o.solver = "dlx" }), false,
+		},
+		{"Unknown solver", []string{"-solver", "magic", "file"}, options{}, true},
+		{
+			"Timeout", []string{"--timeout", "2s", "file"},
+			defaultOptions(func(o *options) { o.timeout = 2 * time.Second }), false,
+		},
+		{"Negative timeout", []string{"--timeout", "-1s", "file"}, options{}, true},
+		{"Invalid timeout", []string{"--timeout", "soon", "file"}, options{}, true},
+		{
+			"Parallel", []string{"--parallel", "--sizes", "3", "--deterministic", "file"},
+			defaultOptions(func(o *options) {
+				o.parallel = true
+				o.parallelOpts = ParallelOptions{Sizes: 3, Deterministic: true}
+			}), false,
+		},
+		{
+			"Rotate and mirror", []string{"--rotate", "--mirror", "file"},
+			defaultOptions(func(o *options) { o.transforms = tetris.Rotate | tetris.Mirror }), false,
+		},
+		{
+			"Mirror only", []string{"--mirror", "file"},
+			defaultOptions(func(o *options) { o.transforms = tetris.Mirror }), false,
+		},
+		{
+			"Numeric output with custom alphabet", []string{"--output-format", "numeric", "--alphabet", "xyz", "file"},
+			defaultOptions(func(o *options) {
+				o.outputFormat = "numeric"
+				o.alphabet = "xyz"
+			}), false,
+		},
+		{
+			"Container", []string{"--container", "shape", "file"},
+			defaultOptions(func(o *options) { o.container = "shape" }), false,
+		},
+		{
+			"Rectangle", []string{"--rectangle", "--max-aspect", "1.5", "file"},
+			defaultOptions(func(o *options) {
+				o.rectangle = true
+				o.maxAspect = 1.5
+			}), false,
+		},
+		{"Aspect below 1", []string{"--rectangle", "--max-aspect", "0.5", "file"}, options{}, true},
+		{"Aspect without rectangle", []string{"--max-aspect", "2", "file"}, options{}, true},
+		{"Rectangle and container", []string{"--rectangle", "--container", "shape", "file"}, options{}, true},
+		{
+			"Certificate", []string{"--certificate", "cert", "file"},
+			defaultOptions(func(o *options) { o.certificate = "cert" }), false,
+		},
+		{"Certificate for rectangle", []string{"--rectangle", "--certificate", "cert", "file"}, options{}, true},
+		{
+			"JSON formats", []string{"--input-format", "json", "--output-format", "json", "file"},
+			defaultOptions(func(o *options) {
+				o.inputFormat = "json"
+				o.outputFormat = "json"
+			}), false,
+		},
+		{
+			"All errors", []string{"--all-errors", "file"},
+			defaultOptions(func(o *options) { o.parse.CollectAll = true }), false,
+		},
+		{
+			"Lenient glyphs", []string{"--lenient", "--filled", "█", "--empty", "-", "file"},
+			defaultOptions(func(o *options) { o.parse = ParseOptions{Lenient: true, Filled: '█', Empty: '-'} }), false,
+		},
+		{"Glyphs without lenient", []string{"--filled", "X", "file"}, options{}, true},
+		{"Long glyph", []string{"--lenient", "--filled", "XX", "file"}, options{}, true},
+		{"Same glyphs", []string{"--lenient", "--filled", "X", "--empty", "X", "file"}, options{}, true},
+		{"Whitespace glyph", []string{"--lenient", "--empty", " ", "file"}, options{}, true},
+		{"Verbose", []string{"--verbose", "file"}, defaultOptions(func(o *options) { o.verbose = true }), false},
+		{"Stats", []string{"--stats", "file"}, defaultOptions(func(o *options) { o.stats = true }), false},
+		{"Stats for container", []string{"--stats", "--container", "shape", "file"}, options{}, true},
+		{"Unknown input format", []string{"--input-format", "yaml", "file"}, options{}, true},
+		{"Unknown output format", []string{"--output-format", "xml", "file"}, options{}, true},
+		{"Invalid alphabet", []string{"--alphabet", "AA", "file"}, options{}, true},
+		{"Empty alphabet", []string{"--alphabet", "", "file"}, options{}, true},
+		{"Zero sizes", []string{"--parallel", "--sizes", "0", "file"}, options{}, true},
+		{"Missing path", []string{}, options{}, true},
+		{"Too many paths", []string{"a", "b"}, options{}, true},
+		{"Unknown flag", []string{"-fast", "file"}, options{}, true},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := parseArgs(test.args)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected error, got options %+v", opts)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if opts != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, opts)
+			}
+		})
+	}
+}
+
+func TestInitPolyominoPieces(t *testing.T) {
+	raws := []tetris.RawPiece{{[]byte("#####")}, {[]byte("#.."), []byte("###")}, {[]byte("#")}}
+	pieces, err := initPolyominoPieces(raws, 26)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, expected := range []int{5, 4, 1} {
+		if len(pieces[i].Pos) != expected {
+			t.Errorf("expected piece %d to have %d blocks, got %d", pieces[i].ID, expected, len(pieces[i].Pos))
+		}
+	}
+
+	_, err = initPolyominoPieces(makeRaws(raws[2], 27), 26)
+	if err == nil || err.Error() != "cannot process more than 26 polyominoes" {
+		t.Errorf("expected limit error, got %v", err)
+	}
+}
+
+func TestReadPiecesErrors(t *testing.T) {
+	const iPiece = "####\n....\n....\n....\n\n"
+
+	testData := []struct {
+		name      string
+		input     string
+		polyomino bool
+		parse     ParseOptions
+		errMsg    string
+	}{
+		{
+			"Bad character", iPiece + "#x..\n#...\n##..\n....\n", false, ParseOptions{},
+			"invalid file format; line 6, column 2, piece 2: unrecognised character 'x'",
+		},
+		{
+			"Block count", iPiece + "##..\n#...\n....\n....\n", false, ParseOptions{},
+			"invalid file format; line 6, piece 2: tetromino should have 4 blocks",
+		},
+		{
+			"Disconnected", "##..\n....\n.##.\n....\n", false, ParseOptions{},
+			"invalid file format; line 3, column 2, piece 1: invalid tetromino",
+		},
+		{"Disconnected polyomino", "#\n\n#.\n.#\n", true, ParseOptions{}, "invalid file format; line 4, column 2, piece 2: invalid polyomino"},
+		{
+			"Lenient glyphs", "AA..\r\n....\r\n..AA\r\n....\r\n", false, ParseOptions{Lenient: true, Filled: 'A'},
+			"invalid file format; line 3, column 3, piece 1: invalid tetromino",
+		},
+		{
+			"All errors", "###\n\n" + iPiece + "#x..\n#...\n##..\n....\n\n#...\n....\n....\n....\n", false, ParseOptions{CollectAll: true},
+			"invalid file format; line 1, column 4, piece 1: Tetromino should have 4 columns\n" +
+				"invalid file format; line 8, column 2, piece 3: unrecognised character 'x'\n" +
+				"invalid file format; line 13, piece 4: tetromino should have 4 blocks",
+		},
+		{"Limit", strings.Repeat(iPiece, 3), false, ParseOptions{}, "cannot process more than 2 tetrominoes"},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := readPieces(strings.NewReader(test.input), test.polyomino, 2, test.parse)
+			if err == nil || err.Error() != test.errMsg {
+				t.Fatalf("expected error %q, got %v", test.errMsg, err)
+			}
+		})
+	}
+
+	_, err := readPieces(strings.NewReader("#x..\n#...\n##..\n....\n"), false, 0, ParseOptions{})
+
+	var glyphErr *tetris.GlyphError
+	if !errors.As(err, &glyphErr) {
+		t.Errorf("expected the error to wrap a *tetris.GlyphError, got %v", err)
+	}
+}
+
+func TestPieceLimit(t *testing.T) {
+	testData := []struct {
+		name     string
+		opts     options
+		expected int
+	}{
+		{"Default alphabet", defaultOptions(nil), 62},
+		{"Custom alphabet", defaultOptions(func(o *options) { o.alphabet = "αβγ" }), 3},
+		{"Numeric output", defaultOptions(func(o *options) { o.outputFormat = "numeric" }), 0},
+		{"JSON output", defaultOptions(func(o *options) { o.outputFormat = "json" }), 0},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			if got := pieceLimit(test.opts); got != test.expected {
+				t.Errorf("pieceLimit() = %d, want %d", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestShapeSummary(t *testing.T) {
+	testData := []struct {
+		name      string
+		input     string
+		polyomino bool
+		expected  string
+	}{
+		{"Most common first", strings.Repeat("#...\n#...\n##..\n....\n\n", 2) + "##..\n##..\n....\n....\n", false, "2×L, 1×O"},
+		{"Ties in shape order", "...#\n...#\n..##\n....\n\n###.\n.#..\n....\n....\n", false, "1×T, 1×J"},
+		{"Other polyominoes last", "#\n\n#\n\n####\n", true, "1×I, 2×other"},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			pieces, err := readPieces(strings.NewReader(test.input), test.polyomino, 0, ParseOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := shapeSummary(pieces); got != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
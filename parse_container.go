@@ -0,0 +1,70 @@
+// Package main contains file parsing for container shapes.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+
+	"tetris-optimizer/tetris"
+)
+
+// ParseContainer reads a container shape from a scanner.
+// The container is a grid of rows with equal length, where '.' marks a cell
+// pieces may cover and '#' a blocked cell. Trailing blank lines are ignored.
+func ParseContainer(scanner *bufio.Scanner) (tetris.Board, error) {
+	if scanner == nil {
+		return tetris.Board{}, errors.New("scanner should not be nil")
+	}
+
+	var rows []string
+	blank := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if len(line) == 0 {
+			blank = len(rows) > 0
+			continue
+		}
+
+		if blank {
+			return tetris.Board{}, errors.New("invalid file format; Container should not contain blank lines")
+		}
+
+		if len(rows) > 0 && len(line) != len(rows[0]) {
+			return tetris.Board{}, errors.New("invalid file format; Container rows should have equal length")
+		}
+
+		rows = append(rows, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return tetris.Board{}, err
+	}
+
+	if len(rows) == 0 {
+		return tetris.Board{}, errors.New("invalid file format; Container should not be empty")
+	}
+
+	if len(rows) > tetris.MaxSize || len(rows[0]) > tetris.MaxSize {
+		return tetris.Board{}, fmt.Errorf("invalid file format; Container should be at most %d cells wide and tall",
+			tetris.MaxSize)
+	}
+
+	board := tetris.NewRectBoard(uint(len(rows[0])), uint(len(rows)))
+
+	for y, row := range rows {
+		for x, char := range []byte(row) {
+			switch char {
+			case '.':
+			case '#':
+				board.Block(x, y)
+			default:
+				return tetris.Board{}, fmt.Errorf("invalid file format; unrecognised character '%c' in container", char)
+			}
+		}
+	}
+
+	return board, nil
+}
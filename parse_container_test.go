@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestParseContainer(t *testing.T) {
+	testData := []struct {
+		name        string
+		input       string
+		expected    string
+		expectError bool
+		expectedMsg string
+	}{
+		{
+			name:     "Valid: Rectangle",
+			input:    "....\n....\n",
+			expected: "....\n....\n",
+		},
+		{
+			name:     "Valid: Blocked cells and trailing blank lines",
+			input:    "#..\n...\n..#\n\n\n",
+			expected: "#..\n...\n..#\n",
+		},
+		{
+			name:     "Valid: Leading blank lines and no final newline",
+			input:    "\n.#.",
+			expected: ".#.\n",
+		},
+		{
+			name:        "Invalid: Empty",
+			input:       "\n\n",
+			expectError: true,
+			expectedMsg: "invalid file format; Container should not be empty",
+		},
+		{
+			name:        "Invalid: Ragged rows",
+			input:       "...\n..\n",
+			expectError: true,
+			expectedMsg: "invalid file format; Container rows should have equal length",
+		},
+		{
+			name:        "Invalid: Blank line inside",
+			input:       "...\n\n...\n",
+			expectError: true,
+			expectedMsg: "invalid file format; Container should not contain blank lines",
+		},
+		{
+			name:        "Invalid: Unknown character",
+			input:       "..A\n...\n",
+			expectError: true,
+			expectedMsg: "invalid file format; unrecognised character 'A' in container",
+		},
+		{
+			name:        "Invalid: Too wide",
+			input:       strings.Repeat(".", 65) + "\n",
+			expectError: true,
+			expectedMsg: "invalid file format; Container should be at most 64 cells wide and tall",
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			board, err := ParseContainer(bufio.NewScanner(strings.NewReader(test.input)))
+
+			if test.expectError {
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+
+				if err.Error() != test.expectedMsg {
+					t.Errorf("expected error %q, got %q", test.expectedMsg, err.Error())
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if output := board.ToString(); output != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, output)
+			}
+		})
+	}
+
+	t.Run("Nil scanner", func(t *testing.T) {
+		if _, err := ParseContainer(nil); err == nil {
+			t.Fatal("expected error for nil scanner")
+		}
+	})
+}
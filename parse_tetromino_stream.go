@@ -1,33 +1,340 @@
-// Package main contains file parsing for tetromino input.
+// Package main contains file parsing for tetromino and polyomino input.
 package main
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
 	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
 
 	"tetris-optimizer/tetris"
 )
 
-// ParseTetrominoStream reads tetrominoes from a scanner (4 rows × 4 cols, separated by blanks).
-func ParseTetrominoStream(scanner *bufio.Scanner) (pieces []tetris.RawPiece, err error) {
-	if scanner == nil {
-		return nil, errors.New("scanner should not be nil")
+// ParseOptions configures ParseTetrominoStream and ParsePolyominoStream.
+type ParseOptions struct {
+	CollectAll bool // Report every error in one pass instead of stopping at the first
+	Lenient    bool // Accept any line ending and trailing whitespace, and read Filled and Empty glyphs
+	Filled     rune // Glyph of a block in lenient mode, '#' if zero
+	Empty      rune // Glyph of an empty cell in lenient mode, '.' if zero
+}
+
+// glyphs returns the block and empty cell glyphs, with defaults filled in.
+func (o ParseOptions) glyphs() (filled, empty rune) {
+	return cmp.Or(o.Filled, '#'), cmp.Or(o.Empty, '.')
+}
+
+// validate checks that the glyphs can be told apart from each other and from whitespace.
+func (o ParseOptions) validate() error {
+	filled, empty := o.glyphs()
+
+	if unicode.IsSpace(filled) || unicode.IsSpace(empty) {
+		return errors.New("glyphs should not be whitespace")
+	}
+
+	if filled == empty {
+		return errors.New("filled and empty glyphs should differ")
+	}
+
+	return nil
+}
+
+// scanAnyLines is like bufio.ScanLines but also ends lines at a lone '\r'.
+func scanAnyLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	i := bytes.IndexAny(data, "\r\n")
+
+	switch {
+	case i < 0 && atEOF:
+		return len(data), data, nil
+	case i < 0:
+		return 0, nil, nil // Request more data.
+	case data[i] == '\n':
+		return i + 1, data[:i], nil
+	case i+1 < len(data) && data[i+1] == '\n':
+		return i + 2, data[:i], nil
+	case i+1 < len(data) || atEOF:
+		return i + 1, data[:i], nil
+	}
+
+	return 0, nil, nil // A '\r' at the end of the buffer may start a "\r\n".
+}
+
+// ParseError locates a malformed part of an input file.
+type ParseError struct {
+	Line   int    // 1-based line number
+	Column int    // 1-based column, 0 when the whole line is at fault
+	Piece  int    // 0-based index of the piece being read
+	Msg    string // Description of the problem
+	Err    error  // Validation error of a well-formed piece, nil for format errors
+}
+
+// Error implements error. Pieces are numbered from 1 in the message.
+func (e *ParseError) Error() string {
+	if e.Column == 0 {
+		return fmt.Sprintf("invalid file format; line %d, piece %d: %s", e.Line, e.Piece+1, e.Msg)
+	}
+
+	return fmt.Sprintf("invalid file format; line %d, column %d, piece %d: %s", e.Line, e.Column, e.Piece+1, e.Msg)
+}
+
+// Unwrap returns the validation error, so errors.As finds e.g. a *tetris.GlyphError.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors is every error found in a file when ParseOptions.CollectAll is set.
+type ParseErrors []*ParseError
+
+// Error implements error with one line per error.
+func (e ParseErrors) Error() string {
+	msgs := make([]string, len(e))
+
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the individual errors for errors.Is and errors.As.
+func (e ParseErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+
+	for i, err := range e {
+		errs[i] = err
+	}
+
+	return errs
+}
+
+// lineParser tracks the position in the input and the errors found so far.
+type lineParser struct {
+	opts    ParseOptions
+	scanner *bufio.Scanner
+	line    int  // Number of the current line
+	piece   int  // Index of the piece being read
+	glyph   rune // First unrecognised glyph of the current line in lenient mode
+	column  int  // Column of glyph, 0 if there is none
+	first   int  // First line of the piece being read
+	starts  []rawStart
+	errs    ParseErrors
+}
+
+// rawStart is where a piece that was read correctly starts in the input.
+type rawStart struct {
+	line  int // First line of the piece
+	piece int // Index of the piece among all pieces, malformed ones included
+}
+
+// newLineParser returns a parser reading r line by line.
+func newLineParser(r io.Reader, opts ParseOptions) (*lineParser, error) {
+	if r == nil {
+		return nil, errors.New("reader should not be nil")
 	}
 
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(r)
+	if opts.Lenient {
+		scanner.Split(scanAnyLines)
+	}
+
+	return &lineParser{opts: opts, scanner: scanner}, nil
+}
+
+// next advances to the next line, reporting false at the end of the input.
+// In lenient mode the line is normalised to '#' and '.' glyphs.
+func (p *lineParser) next() (string, bool) {
+	if !p.scanner.Scan() {
+		return "", false
+	}
+
+	p.line++
+	if !p.opts.Lenient {
+		return p.scanner.Text(), true
+	}
+
+	return p.normalize(p.scanner.Text()), true
+}
+
+// normalize strips a byte order mark and trailing whitespace from a line and
+// translates its glyphs. Unrecognised glyphs become '?' and the first is recorded.
+func (p *lineParser) normalize(line string) string {
+	if p.line == 1 {
+		line = strings.TrimPrefix(line, "\uFEFF")
+	}
+
+	filled, empty := p.opts.glyphs()
+	row := make([]byte, 0, len(line))
+	p.column = 0
+
+	for _, r := range strings.TrimRightFunc(line, unicode.IsSpace) {
+		switch r {
+		case filled:
+			row = append(row, '#')
+		case empty:
+			row = append(row, '.')
+		default:
+			if p.column == 0 {
+				p.glyph, p.column = r, len(row)+1
+			}
+
+			row = append(row, '?')
+		}
+	}
+
+	return string(row)
+}
+
+// checkRow returns the column and description of the first problem with a row
+// of a piece that should be width cells wide, or 0 if the row is valid.
+func (p *lineParser) checkRow(line string, width int, msg string) (int, string) {
+	if p.column > 0 {
+		return p.column, fmt.Sprintf("unrecognised character '%c'", p.glyph)
+	}
+
+	if len(line) != width {
+		return min(len(line), width) + 1, msg
+	}
+
+	return 0, ""
+}
+
+// fail records an error at column of the current line and reports whether parsing should stop.
+func (p *lineParser) fail(column int, msg string) bool {
+	p.errs = append(p.errs, &ParseError{Line: p.line, Column: column, Piece: p.piece, Msg: msg})
+	return !p.opts.CollectAll
+}
+
+// add appends the piece read since line p.first to pieces.
+func (p *lineParser) add(pieces []tetris.RawPiece, piece tetris.RawPiece) []tetris.RawPiece {
+	p.starts = append(p.starts, rawStart{line: p.first, piece: p.piece})
+	return append(pieces, piece)
+}
+
+// result returns the pieces, or the first error, or all errors in CollectAll mode.
+func (p *lineParser) result(pieces []tetris.RawPiece) ([]tetris.RawPiece, error) {
+	if err := p.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(p.errs) == 0:
+		return pieces, nil
+	case p.opts.CollectAll:
+		return pieces, p.errs
+	default:
+		return nil, p.errs[0]
+	}
+}
+
+// validate converts the raw pieces read by p with init, assigning IDs in input
+// order. A piece init rejects is recorded as a *ParseError on the line and column
+// of the block at fault, or on its first line. Errors stop validation unless
+// CollectAll is set.
+func (p *lineParser) validate(
+	raws []tetris.RawPiece, init func(tetris.RawPiece, int) (tetris.Piece, error),
+) ([]tetris.Piece, error) {
+	var pieces []tetris.Piece
+
+	for id, raw := range raws {
+		if len(p.errs) > 0 && !p.opts.CollectAll {
+			break
+		}
+
+		piece, err := init(raw, id)
+		if err != nil {
+			start := p.starts[id]
+			e := &ParseError{Line: start.line, Piece: start.piece, Msg: err.Error(), Err: err}
+
+			if at, ok := blockAtFault(err); ok {
+				e.Line, e.Column = start.line+at.Y, at.X+1
+			}
+
+			p.errs = append(p.errs, e)
+			continue
+		}
+
+		pieces = append(pieces, piece)
+	}
+
+	// Validation runs after parsing, so its errors are put back in file order.
+	slices.SortStableFunc(p.errs, func(a, b *ParseError) int { return cmp.Compare(a.Line, b.Line) })
+
+	if _, err := p.result(nil); err != nil {
+		return nil, err
+	}
+
+	return pieces, nil
+}
+
+// blockAtFault returns the position in the raw grid that a validation error points at, if any.
+func blockAtFault(err error) (tetris.Point, bool) {
+	var glyphErr *tetris.GlyphError
+	var disconnectedErr *tetris.DisconnectedError
+
+	switch {
+	case errors.As(err, &glyphErr):
+		return glyphErr.Pos, true
+	case errors.As(err, &disconnectedErr) && len(disconnectedErr.Stray) > 0:
+		return disconnectedErr.Stray[0], true
+	}
+
+	return tetris.Point{}, false
+}
+
+// ParseTetrominoStream reads tetrominoes from r (4 rows × 4 cols, separated by blanks).
+// Errors are *ParseError, or ParseErrors with every error if opts.CollectAll is set.
+// In that case the pieces that were read correctly are returned alongside the errors.
+func ParseTetrominoStream(r io.Reader, opts ParseOptions) (pieces []tetris.RawPiece, err error) {
+	p, err := newLineParser(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.result(p.tetrominoes())
+}
+
+// tetrominoes reads the tetrominoes of ParseTetrominoStream, recording errors in p.
+func (p *lineParser) tetrominoes() (pieces []tetris.RawPiece) {
 	var current tetris.RawPiece
 	rowCount := 0
+	skipping := false // Skip the rest of a malformed piece after an error
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	for {
+		line, ok := p.next()
+		if !ok {
+			break
+		}
+
+		if skipping {
+			skipping = len(line) != 0
+			continue
+		}
 
 		// Allow back-to-back tetrominoes without a blank separator.
 		if rowCount == 4 {
-			pieces = append(pieces, current)
-			current = tetris.RawPiece{}
+			pieces = p.add(pieces, current)
+			current = nil
 			rowCount = 0
+			p.piece++
 
 			if len(line) != 0 {
-				return nil, errors.New("invalid file format; Tetrominoes should be separated by blank lines")
+				if p.fail(1, "Tetrominoes should be separated by blank lines") {
+					return nil
+				}
+
+				skipping = true
 			}
 
 			continue
@@ -38,27 +345,116 @@ func ParseTetrominoStream(scanner *bufio.Scanner) (pieces []tetris.RawPiece, err
 				continue // Allow for several blank lines between tetrominoes.
 			}
 
-			return nil, errors.New("invalid file format; Tetromino should have 4 rows")
+			if p.fail(0, "Tetromino should have 4 rows") {
+				return nil
+			}
+
+			current = nil
+			rowCount = 0
+			p.piece++
+
+			continue
 		}
 
-		if len(line) != 4 {
-			return nil, errors.New("invalid file format; Tetromino should have 4 columns")
+		if column, msg := p.checkRow(line, 4, "Tetromino should have 4 columns"); column > 0 {
+			if p.fail(column, msg) {
+				return nil
+			}
+
+			current = nil
+			rowCount = 0
+			p.piece++
+			skipping = true
+
+			continue
 		}
 
-		copy(current[rowCount][:], []byte(line))
-		rowCount++
-	}
+		if rowCount == 0 {
+			p.first = p.line
+		}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+		current = append(current, []byte(line))
+		rowCount++
 	}
 
 	// Add final tetromino if present
 	if rowCount == 4 {
-		pieces = append(pieces, current)
+		pieces = p.add(pieces, current)
 	} else if rowCount > 0 {
-		return nil, errors.New("invalid file format; Tetromino should have 4 rows")
+		p.fail(0, "Tetromino should have 4 rows")
 	}
 
-	return pieces, nil
+	return pieces
+}
+
+// ParsePolyominoStream reads pieces of any size from r.
+// Each piece is a rectangular grid of rows with equal length, separated by blanks.
+// Errors are reported as by ParseTetrominoStream.
+func ParsePolyominoStream(r io.Reader, opts ParseOptions) (pieces []tetris.RawPiece, err error) {
+	p, err := newLineParser(r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.result(p.polyominoes())
+}
+
+// polyominoes reads the polyominoes of ParsePolyominoStream, recording errors in p.
+func (p *lineParser) polyominoes() (pieces []tetris.RawPiece) {
+	var current tetris.RawPiece
+	skipping := false // Skip the rest of a malformed piece after an error
+
+	for {
+		line, ok := p.next()
+		if !ok {
+			break
+		}
+
+		if len(line) == 0 {
+			if current != nil || skipping {
+				if current != nil {
+					pieces = p.add(pieces, current)
+				}
+
+				current = nil
+				skipping = false
+				p.piece++
+			}
+
+			continue // Allow for several blank lines between polyominoes.
+		}
+
+		if skipping {
+			continue
+		}
+
+		width := len(line)
+		if current != nil {
+			width = len(current[0])
+		}
+
+		if column, msg := p.checkRow(line, width, "Polyomino rows should have equal length"); column > 0 {
+			if p.fail(column, msg) {
+				return nil
+			}
+
+			current = nil
+			skipping = true
+
+			continue
+		}
+
+		if current == nil {
+			p.first = p.line
+		}
+
+		current = append(current, []byte(line))
+	}
+
+	// Add final polyomino if present
+	if current != nil {
+		pieces = p.add(pieces, current)
+	}
+
+	return pieces
 }
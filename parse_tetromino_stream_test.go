@@ -2,20 +2,21 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"tetris-optimizer/tetris"
 )
 
 func makeRaw(char byte) tetris.RawPiece {
-	var tet tetris.RawPiece
+	tet := make(tetris.RawPiece, 4)
 
-	for y := range 4 {
-		for x := range 4 {
-			tet[y][x] = char
-		}
+	for y := range tet {
+		tet[y] = []byte{char, char, char, char}
 	}
 
 	return tet
@@ -58,42 +59,42 @@ func TestParseTetrominoStream(t *testing.T) {
 			name:        "Invalid: Columns < 4",
 			input:       "123\n" + strings.Repeat("1234\n", 3),
 			expectError: true,
-			expectedMsg: "invalid file format; Tetromino should have 4 columns",
+			expectedMsg: "invalid file format; line 1, column 4, piece 1: Tetromino should have 4 columns",
 		},
 		{
 			name:        "Invalid: Columns > 4",
 			input:       "12345\n" + strings.Repeat("1234\n", 3),
 			expectError: true,
-			expectedMsg: "invalid file format; Tetromino should have 4 columns",
+			expectedMsg: "invalid file format; line 1, column 5, piece 1: Tetromino should have 4 columns",
 		},
 		{
 			name:        "Invalid: EOF after 3 lines",
 			input:       strings.Repeat("1234\n", 3),
 			expectError: true,
-			expectedMsg: "invalid file format; Tetromino should have 4 rows",
+			expectedMsg: "invalid file format; line 3, piece 1: Tetromino should have 4 rows",
 		},
 		{
 			name:        "Invalid: Rows < 4",
 			input:       strings.Repeat("1234\n", 2) + "\n1234\n",
 			expectError: true,
-			expectedMsg: "invalid file format; Tetromino should have 4 rows",
+			expectedMsg: "invalid file format; line 3, piece 1: Tetromino should have 4 rows",
 		},
 		{
 			name:        "Invalid: Rows > 4",
 			input:       makeBlock('1') + "1234\n",
 			expectError: true,
-			expectedMsg: "invalid file format; Tetrominoes should be separated by blank lines",
+			expectedMsg: "invalid file format; line 5, column 1, piece 2: Tetrominoes should be separated by blank lines",
 		},
 		{
 			name:        "Invalid: no separator between tetrominoes",
 			input:       makeBlock('1') + makeBlock('2'),
 			expectError: true,
-			expectedMsg: "invalid file format; Tetrominoes should be separated by blank lines",
+			expectedMsg: "invalid file format; line 5, column 1, piece 2: Tetrominoes should be separated by blank lines",
 		},
 	}
 
 	t.Run("nil stream", func(t *testing.T) {
-		_, err := ParseTetrominoStream(nil)
+		_, err := ParseTetrominoStream(nil, ParseOptions{})
 		if err == nil {
 			t.Error("expected error for nil stream, got nil")
 		}
@@ -101,8 +102,7 @@ func TestParseTetrominoStream(t *testing.T) {
 
 	for _, test := range testData {
 		t.Run(test.name, func(t *testing.T) {
-			scanner := bufio.NewScanner(strings.NewReader(test.input))
-			output, err := ParseTetrominoStream(scanner)
+			output, err := ParseTetrominoStream(strings.NewReader(test.input), ParseOptions{})
 
 			if test.expectError {
 				if err == nil {
@@ -124,3 +124,270 @@ func TestParseTetrominoStream(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePolyominoStream(t *testing.T) {
+	testData := []struct {
+		name        string
+		input       string
+		expected    []tetris.RawPiece
+		expectError bool
+		expectedMsg string
+	}{
+		{
+			name:     "Valid: Mixed sizes",
+			input:    "#####\n\n##\n##\n\n#\n",
+			expected: []tetris.RawPiece{{[]byte("#####")}, {[]byte("##"), []byte("##")}, {[]byte("#")}},
+		},
+		{
+			name:     "Valid: Multiple empty line separators without trailing newline",
+			input:    "\n\n#.\n##\n\n\n\n.#",
+			expected: []tetris.RawPiece{{[]byte("#."), []byte("##")}, {[]byte(".#")}},
+		},
+		{
+			name:     "Valid: Empty input",
+			input:    "",
+			expected: nil,
+		},
+		{
+			name:        "Invalid: Ragged rows",
+			input:       "###\n#\n",
+			expectError: true,
+			expectedMsg: "invalid file format; line 2, column 2, piece 1: Polyomino rows should have equal length",
+		},
+	}
+
+	t.Run("nil stream", func(t *testing.T) {
+		_, err := ParsePolyominoStream(nil, ParseOptions{})
+		if err == nil {
+			t.Error("expected error for nil stream, got nil")
+		}
+	})
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := ParsePolyominoStream(strings.NewReader(test.input), ParseOptions{})
+
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected error %q, but got nil", test.expectedMsg)
+				} else if err.Error() != test.expectedMsg {
+					t.Errorf("expected error %q, got %q", test.expectedMsg, err.Error())
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(output, test.expected) {
+				t.Errorf("expected output:\n%+q\ngot:\n%+q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	input := makeBlock('A') + "\nAAA\n"
+
+	_, err := ParseTetrominoStream(strings.NewReader(input), ParseOptions{})
+
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a *ParseError, got %v", err)
+	}
+
+	expected := ParseError{Line: 6, Column: 4, Piece: 1, Msg: "Tetromino should have 4 columns"}
+	if *parseErr != expected {
+		t.Errorf("expected %+v, got %+v", expected, *parseErr)
+	}
+}
+
+func TestParseCollectAll(t *testing.T) {
+	testData := []struct {
+		name      string
+		parse     func(io.Reader, ParseOptions) ([]tetris.RawPiece, error)
+		input     string
+		expected  []tetris.RawPiece
+		positions []ParseError // Line, column and piece of each error
+	}{
+		{
+			name:     "Tetrominoes: valid pieces between errors are kept",
+			parse:    ParseTetrominoStream,
+			input:    "AAA\nAAAA\n\n" + makeBlock('B') + "\nCCCC\nCCCC\n\n" + makeBlock('D') + "DDDD\n",
+			expected: []tetris.RawPiece{makeRaw('B'), makeRaw('D')},
+			positions: []ParseError{
+				{Line: 1, Column: 4, Piece: 0},
+				{Line: 11, Column: 0, Piece: 2},
+				{Line: 16, Column: 1, Piece: 4},
+			},
+		},
+		{
+			name:     "Tetrominoes: short final piece",
+			parse:    ParseTetrominoStream,
+			input:    makeBlock('A') + "\nBBBBB\n\nCC\n",
+			expected: []tetris.RawPiece{makeRaw('A')},
+			positions: []ParseError{
+				{Line: 6, Column: 5, Piece: 1},
+				{Line: 8, Column: 3, Piece: 2},
+			},
+		},
+		{
+			name:     "Polyominoes",
+			parse:    ParsePolyominoStream,
+			input:    "##\n#\n##\n\n#\n\n###\n####\n",
+			expected: []tetris.RawPiece{{[]byte("#")}},
+			positions: []ParseError{
+				{Line: 2, Column: 2, Piece: 0},
+				{Line: 8, Column: 4, Piece: 2},
+			},
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := test.parse(strings.NewReader(test.input), ParseOptions{CollectAll: true})
+
+			var errs ParseErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected ParseErrors, got %v", err)
+			}
+
+			if len(errs) != len(test.positions) {
+				t.Fatalf("expected %d errors, got %d:\n%v", len(test.positions), len(errs), err)
+			}
+
+			for i, e := range errs {
+				got := ParseError{Line: e.Line, Column: e.Column, Piece: e.Piece}
+				if got != test.positions[i] {
+					t.Errorf("error %d: expected position %+v, got %+v", i, test.positions[i], got)
+				}
+			}
+
+			var first *ParseError
+			if !errors.As(err, &first) || first != errs[0] {
+				t.Errorf("expected errors.As to find the first error")
+			}
+
+			if !reflect.DeepEqual(output, test.expected) {
+				t.Errorf("expected output:\n%+q\ngot:\n%+q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	testData := []struct {
+		name        string
+		parse       func(io.Reader, ParseOptions) ([]tetris.RawPiece, error)
+		opts        ParseOptions
+		input       string
+		expected    []tetris.RawPiece
+		expectedMsg string
+	}{
+		{
+			name:     "CRLF and trailing whitespace",
+			parse:    ParseTetrominoStream,
+			opts:     ParseOptions{Lenient: true},
+			input:    "#...  \r\n#...\t\r\n##..\r\n....\r\n \r\n....\r\n.##.\r\n.##.\r\n....",
+			expected: []tetris.RawPiece{rawRows("#...", "#...", "##..", "...."), rawRows("....", ".##.", ".##.", "....")},
+		},
+		{
+			name:     "CR line endings and byte order mark",
+			parse:    ParseTetrominoStream,
+			opts:     ParseOptions{Lenient: true},
+			input:    "\uFEFF####\r....\r....\r....\r",
+			expected: []tetris.RawPiece{rawRows("####", "....", "....", "....")},
+		},
+		{
+			name:     "Custom glyphs",
+			parse:    ParseTetrominoStream,
+			opts:     ParseOptions{Lenient: true, Filled: '█', Empty: '·'},
+			input:    "█···\n█···\n██··\n····\n",
+			expected: []tetris.RawPiece{rawRows("#...", "#...", "##..", "....")},
+		},
+		{
+			name:        "Default glyphs are rejected when replaced",
+			parse:       ParseTetrominoStream,
+			opts:        ParseOptions{Lenient: true, Filled: 'X', Empty: 'o'},
+			input:       "Xooo\nX#oo\nXXoo\noooo\n",
+			expectedMsg: "invalid file format; line 2, column 2, piece 1: unrecognised character '#'",
+		},
+		{
+			name:     "Polyominoes",
+			parse:    ParsePolyominoStream,
+			opts:     ParseOptions{Lenient: true, Filled: 'x', Empty: '_'},
+			input:    "x_ \r\nxx\r\n\r\nxxx\r\n",
+			expected: []tetris.RawPiece{rawRows("#.", "##"), rawRows("###")},
+		},
+		{
+			name:        "Polyomino glyph error",
+			parse:       ParsePolyominoStream,
+			opts:        ParseOptions{Lenient: true},
+			input:       "##\n#.\n\n#é#\n",
+			expectedMsg: "invalid file format; line 4, column 2, piece 2: unrecognised character 'é'",
+		},
+		{
+			name:        "Same glyphs",
+			parse:       ParseTetrominoStream,
+			opts:        ParseOptions{Lenient: true, Filled: '.'},
+			expectedMsg: "filled and empty glyphs should differ",
+		},
+		{
+			name:        "Strict mode keeps trailing whitespace",
+			parse:       ParseTetrominoStream,
+			input:       "#... \n#...\n##..\n....\n",
+			expectedMsg: "invalid file format; line 1, column 5, piece 1: Tetromino should have 4 columns",
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := test.parse(strings.NewReader(test.input), test.opts)
+
+			if test.expectedMsg != "" {
+				if err == nil || err.Error() != test.expectedMsg {
+					t.Errorf("expected error %q, got %v", test.expectedMsg, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(output, test.expected) {
+				t.Errorf("expected output:\n%+q\ngot:\n%+q", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestScanAnyLines(t *testing.T) {
+	// A reader returning one byte at a time splits "\r\n" across reads.
+	scanner := bufio.NewScanner(iotest.OneByteReader(strings.NewReader("a\r\nb\rc\n\r\nd\r")))
+	scanner.Split(scanAnyLines)
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	expected := []string{"a", "b", "c", "", "d"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+// rawRows builds a raw piece from its rows.
+func rawRows(rows ...string) tetris.RawPiece {
+	raw := make(tetris.RawPiece, len(rows))
+
+	for i, row := range rows {
+		raw[i] = []byte(row)
+	}
+
+	return raw
+}
@@ -1,135 +1,371 @@
-// Package main contains the backtracking solver for the tetromino packing problem.
+// Package main contains the board size search for the tetromino packing problem.
 package main
 
 import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
 	"math"
+	"runtime"
 	"slices"
+	"sync"
 	"time"
 
+	"tetris-optimizer/solver"
 	"tetris-optimizer/tetris"
 )
 
-// minimumBoardSize returns the theoretical minimum size: ⌈√(count×4)⌉.
-func minimumBoardSize(tetrominoCount int) int {
-	cellCount := float64(tetrominoCount * 4)
-	root := math.Sqrt(cellCount)
+// ErrInterrupted is returned when the search is cancelled or times out before
+// a solution is found. It wraps the context error.
+var ErrInterrupted = errors.New("search interrupted")
+
+// ErrNoFit is returned when the pieces cannot be packed into a container.
+var ErrNoFit = errors.New("pieces do not fit in the container")
+
+// minimumBoardSize returns the theoretical minimum size: ⌈√cellCount⌉.
+func minimumBoardSize(cellCount int) int {
+	root := math.Sqrt(float64(cellCount))
 	ceil := math.Ceil(root)
 
 	return int(ceil)
 }
 
-// maximumBoardSize returns the upper search bound: ⌈√(count×16)⌉.
-func maximumBoardSize(tetrominoCount int) int {
-	cellCount := float64(tetrominoCount * 16)
-	root := math.Sqrt(cellCount)
+// maximumBoardSize returns an upper search bound that always holds a solution:
+// each piece fits its own boxSide×boxSide box and the boxes are laid out
+// ⌈√pieceCount⌉ to a row.
+func maximumBoardSize(pieceCount, boxSide int) int {
+	root := math.Sqrt(float64(pieceCount))
 	ceil := math.Ceil(root)
 
-	return int(ceil)
+	return int(ceil) * boxSide
 }
 
-// solveCtx holds the state for the timeout mechanism.
-type solveCtx struct {
-	deadline time.Time
-	timedOut bool
-	ops      int // Operation counter to reduce syscall overhead
+// pieceCells returns the number of blocks in all pieces, the least area that can hold them.
+func pieceCells(pieces []tetris.Piece) int {
+	cellCount := 0
+
+	for _, p := range pieces {
+		cellCount += len(p.Pos)
+	}
+
+	return cellCount
 }
 
-// solve recursively places pieces using backtracking with an optional timeout.
-func solve(board *tetris.Board, pieces []tetris.Piece, ctx *solveCtx) bool {
-	// OPTIMIZATION: Check timeout every 1024 iterations.
-	// time.Now() is a syscall; calling it every recursion is too slow.
-	if ctx != nil {
-		ctx.ops++
-		if ctx.ops&1023 == 0 {
-			if ctx.timedOut || time.Now().After(ctx.deadline) {
-				ctx.timedOut = true
-				return false
-			}
-		}
+// boardSizeBounds returns the range of square sizes to search for the pieces.
+// The lower bound accounts for both the total area and the longest piece.
+func boardSizeBounds(pieces []tetris.Piece) (minSize, maxSize int) {
+	longest := 0
+
+	for _, p := range pieces {
+		longest = max(longest, p.Width, p.Height)
 	}
 
-	if len(pieces) == 0 {
-		return true
+	minSize = max(minimumBoardSize(pieceCells(pieces)), longest)
+	maxSize = min(max(maximumBoardSize(len(pieces), longest), minSize), tetris.MaxSize)
+
+	return minSize, maxSize
+}
+
+// FindSmallestSquare finds the smallest square that fits all pieces,
+// trying each size from the theoretical minimum upwards with the given solver.
+// Identical pieces in the solution are labelled in reading order, as relabelled
+// by tetris.Board.Relabel.
+//
+// If ctx is done before a solution is found, the returned error wraps both
+// ErrInterrupted and the context error, and the board holds the largest partial
+// placement found at the size being searched.
+func FindSmallestSquare(ctx context.Context, tetrominoes []tetris.Piece, s solver.Solver) (tetris.Board, error) {
+	board, _, err := FindSmallestSquareStats(ctx, tetrominoes, s)
+	return board, err
+}
+
+// FindSmallestSquareStats is FindSmallestSquare, also returning the stats of
+// every size searched, whether or not the search succeeds.
+func FindSmallestSquareStats(
+	ctx context.Context, tetrominoes []tetris.Piece, s solver.Solver,
+) (tetris.Board, Stats, error) {
+	minSize, maxSize := boardSizeBounds(tetrominoes)
+
+	var stats Stats
+
+	start := time.Now()
+
+	for size := minSize; size <= maxSize; size++ {
+		board := tetris.NewBoard(uint(size))
+
+		var counter solver.Counter
+
+		sizeStart := time.Now()
+		solved := s.Solve(solver.WithCounter(ctx, &counter), &board, tetrominoes)
+
+		stats.Sizes = append(stats.Sizes, SizeStats{
+			Size:        size,
+			SearchStats: counter.Stats(),
+			Elapsed:     time.Since(sizeStart),
+			Solved:      solved,
+			Interrupted: !solved && ctx.Err() != nil,
+		})
+		stats.Elapsed = time.Since(start)
+
+		if solved {
+			board.Relabel(tetrominoes)
+			return board, stats, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return board, stats, fmt.Errorf("%w at size %d: %w", ErrInterrupted, size, err)
+		}
 	}
 
-	current := pieces[0]
-	remaining := pieces[1:]
+	return tetris.Board{}, stats, fmt.Errorf("pieces do not fit in a square of size %d", maxSize)
+}
+
+// rectangle is a candidate board shape.
+type rectangle struct {
+	Width, Height int
+}
+
+// rectangleCandidates returns the board shapes worth searching for the pieces,
+// in order of area, then squareness, with the wider shape first.
+//
+// Every shape has room for the cells of all pieces and for each piece in one of
+// its orientations. maxAspect bounds the ratio of the long side to the short
+// side, zero for no limit. Shapes larger than the biggest square searched by
+// FindSmallestSquare are left out, as that square always holds the pieces.
+func rectangleCandidates(pieces []tetris.Piece, maxAspect float64) []rectangle {
+	cellCount := pieceCells(pieces)
+	_, maxSize := boardSizeBounds(pieces)
+	shapes := orientationShapes(pieces)
+
+	var candidates []rectangle
+
+	for height := 1; height <= tetris.MaxSize; height++ {
+		for width := 1; width <= tetris.MaxSize; width++ {
+			area := width * height
+			long, short := max(width, height), min(width, height)
 
-	// Try all valid positions for the current piece
-	for y := 0; y <= board.Size-current.Height; y++ {
-		for x := 0; x <= board.Size-current.Width; x++ {
-			if !board.CanPlace(current, x, y) {
+			if area < cellCount || area > maxSize*maxSize {
 				continue
 			}
 
-			board.Place(current, x, y)
-			if solve(board, remaining, ctx) {
-				return true
+			if maxAspect > 0 && float64(long) > maxAspect*float64(short) {
+				continue
 			}
-			board.Remove(current, x, y)
 
-			// OPTIMIZATION: If a timeout occurred deeper in the recursion,
-			// break this loop immediately to unwind the stack fast.
-			if ctx != nil && ctx.timedOut {
-				return false
+			if holdsEachPiece(shapes, width, height) {
+				candidates = append(candidates, rectangle{width, height})
 			}
 		}
 	}
 
-	return false
+	slices.SortFunc(candidates, func(a, b rectangle) int {
+		return cmp.Or(
+			cmp.Compare(a.Width*a.Height, b.Width*b.Height),
+			cmp.Compare(max(a.Width, a.Height)-min(a.Width, a.Height), max(b.Width, b.Height)-min(b.Width, b.Height)),
+			cmp.Compare(b.Width, a.Width),
+		)
+	})
+
+	return candidates
 }
 
-// FindSmallestSquare finds the smallest square that fits all tetrominoes.
-// Pieces are sorted using a widest first heuristic to trim decision branches.
-// As the heuristic is not optimal for all cases, a hard timeout is used and
-// the algorithm falls back onto the default ordering.
-func FindSmallestSquare(tetrominoes []tetris.Piece) tetris.Board {
-	tetCount := len(tetrominoes)
-	minSize := minimumBoardSize(tetCount)
-	maxSize := maximumBoardSize(tetCount)
-
-	// OPTIMIZATION: Sort pieces to place the largest/hardest ones first.
-	// This drastically reduces the branching factor of the recursion in some cases.
-	// WARNING: This will also cripple performance of certain cases.
-	sortedPieces := make([]tetris.Piece, len(tetrominoes))
-
-	copy(sortedPieces, tetrominoes)
-	slices.SortFunc(sortedPieces, func(a, b tetris.Piece) int {
-		maxA := max(a.Width, a.Height)
-		maxB := max(b.Width, b.Height)
-		// The subtraction is reversed to cause items to be sorted in descending order.
-		return maxB - maxA
-	})
+// orientationShapes returns the bounding box of every allowed orientation of each piece.
+func orientationShapes(pieces []tetris.Piece) [][]rectangle {
+	shapes := make([][]rectangle, len(pieces))
 
-	useSorted := true
+	for i, p := range pieces {
+		for _, o := range p.Orientations() {
+			shapes[i] = append(shapes[i], rectangle{o.Width, o.Height})
+		}
+	}
 
-	for size := minSize; size <= maxSize; size++ {
-		board := tetris.NewBoard(uint(size))
+	return shapes
+}
 
-		if useSorted {
-			ctx := &solveCtx{deadline: time.Now().Add(500 * time.Millisecond)}
+// holdsEachPiece reports whether a width×height board has room for every piece on its own.
+func holdsEachPiece(shapes [][]rectangle, width, height int) bool {
+	for _, orientations := range shapes {
+		fits := slices.ContainsFunc(orientations, func(r rectangle) bool {
+			return r.Width <= width && r.Height <= height
+		})
 
-			if solve(&board, sortedPieces, ctx) {
-				return board
-			}
+		if !fits {
+			return false
+		}
+	}
 
-			if !ctx.timedOut {
-				continue
-			}
+	return true
+}
+
+// FindSmallestRectangle finds the rectangle of least area that fits all pieces,
+// trying the candidate shapes in order with the given solver. maxAspect bounds
+// the ratio of the long side to the short side, zero for no limit.
+//
+// If ctx is done before a solution is found, the returned error wraps both
+// ErrInterrupted and the context error, and the board holds the largest partial
+// placement found in the shape being searched.
+func FindSmallestRectangle(
+	ctx context.Context, tetrominoes []tetris.Piece, s solver.Solver, maxAspect float64,
+) (tetris.Board, error) {
+	for _, r := range rectangleCandidates(tetrominoes, maxAspect) {
+		board := tetris.NewRectBoard(uint(r.Width), uint(r.Height))
 
-			// TIMEOUT DETECTED: The sorting heuristic is a trap for this puzzle.
-			// Disable it for all larger board sizes to avoid wasting 250ms/loop.
-			useSorted = false
-			// Reset the board as it may be in an incomplete state.
-			board = tetris.NewBoard(uint(size))
+		if s.Solve(ctx, &board, tetrominoes) {
+			board.Relabel(tetrominoes)
+			return board, nil
 		}
 
-		// Fallback (Original Input Order)
-		// passing 'nil' as context effectively disables the timeout checks inside solve
-		if solve(&board, tetrominoes, nil) {
-			return board
+		if err := ctx.Err(); err != nil {
+			return board, fmt.Errorf("%w at size %d×%d: %w", ErrInterrupted, r.Width, r.Height, err)
 		}
 	}
 
-	return tetris.Board{}
+	return tetris.Board{}, errors.New("pieces do not fit in a rectangle within the size and aspect limits")
+}
+
+// ParallelOptions configures FindSmallestSquareParallel.
+type ParallelOptions struct {
+	Workers       int  // Total worker count, zero for runtime.GOMAXPROCS(0)
+	Sizes         int  // Number of board sizes searched at once, at least 1
+	Deterministic bool // Return the same board as the sequential search
+}
+
+// FindSmallestSquareParallel is FindSmallestSquare with the root branches of
+// each size, and optionally several sizes, searched concurrently.
+//
+// Sizes are searched in windows of opts.Sizes. When a size is solved, larger
+// sizes in the window are cancelled while smaller ones run on, so the result
+// is always the smallest square. Every size shares one solver from newSolver,
+// so that state such as the trap of the hybrid solver carries over to larger
+// sizes; it must be safe for concurrent use when opts.Sizes is above 1.
+func FindSmallestSquareParallel(
+	ctx context.Context, tetrominoes []tetris.Piece, newSolver solver.Factory, opts ParallelOptions,
+) (tetris.Board, error) {
+	board, _, err := FindSmallestSquareParallelStats(ctx, tetrominoes, newSolver, opts)
+	return board, err
+}
+
+// FindSmallestSquareParallelStats is FindSmallestSquareParallel, also returning
+// the stats of every size settled. Larger sizes of the last window, cancelled
+// once a smaller one was solved, are left out.
+func FindSmallestSquareParallelStats(
+	ctx context.Context, tetrominoes []tetris.Piece, newSolver solver.Factory, opts ParallelOptions,
+) (tetris.Board, Stats, error) {
+	minSize, maxSize := boardSizeBounds(tetrominoes)
+
+	var stats Stats
+
+	start := time.Now()
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	window := max(1, opts.Sizes)
+	inner := newSolver()
+
+	for first := minSize; first <= maxSize; first += window {
+		count := min(window, maxSize-first+1)
+		boards := make([]tetris.Board, count)
+		solved := make([]bool, count)
+		sizes := make([]SizeStats, count)
+		contexts := make([]context.Context, count)
+		cancels := make([]context.CancelFunc, count)
+
+		for i := range count {
+			boards[i] = tetris.NewBoard(uint(first + i))
+			contexts[i], cancels[i] = context.WithCancel(ctx)
+		}
+
+		var wg sync.WaitGroup
+
+		for i := range count {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				s := solver.Parallel{
+					Inner:         inner,
+					Workers:       max(1, workers/count),
+					Deterministic: opts.Deterministic,
+				}
+
+				var counter solver.Counter
+
+				sizeStart := time.Now()
+				ok := s.Solve(solver.WithCounter(contexts[i], &counter), &boards[i], tetrominoes)
+				sizes[i] = SizeStats{
+					Size:        first + i,
+					SearchStats: counter.Stats(),
+					Elapsed:     time.Since(sizeStart),
+					Solved:      ok,
+				}
+
+				if !ok {
+					return
+				}
+
+				solved[i] = true
+				for _, cancel := range cancels[i+1:] {
+					cancel()
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		for _, cancel := range cancels {
+			cancel()
+		}
+
+		// A larger size only counts once every smaller size is proven infeasible.
+		for i := range count {
+			sizes[i].Interrupted = !solved[i] && ctx.Err() != nil
+			stats.Sizes = append(stats.Sizes, sizes[i])
+			stats.Elapsed = time.Since(start)
+
+			if solved[i] {
+				boards[i].Relabel(tetrominoes)
+				return boards[i], stats, nil
+			}
+
+			if err := ctx.Err(); err != nil {
+				return boards[i], stats, fmt.Errorf("%w at size %d: %w", ErrInterrupted, first+i, err)
+			}
+		}
+	}
+
+	return tetris.Board{}, stats, fmt.Errorf("pieces do not fit in a square of size %d", maxSize)
+}
+
+// FitContainer packs all pieces into the free cells of container with the given solver.
+// The container itself is not modified.
+//
+// If the pieces cannot be packed, the returned error wraps ErrNoFit. If ctx is
+// done first, the error wraps ErrInterrupted and the context error, and the
+// board holds the largest partial placement found.
+func FitContainer(
+	ctx context.Context, tetrominoes []tetris.Piece, container tetris.Board, s solver.Solver,
+) (tetris.Board, error) {
+	cellCount := pieceCells(tetrominoes)
+
+	// OPTIMIZATION: Skip the search when the area alone rules out a packing.
+	if cellCount > container.Free() {
+		return tetris.Board{}, fmt.Errorf("%w: %d cells needed, %d free", ErrNoFit, cellCount, container.Free())
+	}
+
+	board := container.Clone()
+
+	if s.Solve(ctx, &board, tetrominoes) {
+		board.Relabel(tetrominoes)
+		return board, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return board, fmt.Errorf("%w: %w", ErrInterrupted, err)
+	}
+
+	return tetris.Board{}, ErrNoFit
 }
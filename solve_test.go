@@ -1,29 +1,41 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"tetris-optimizer/solver"
 	"tetris-optimizer/tetris"
 )
 
 func TestMinimumBoardSize(t *testing.T) {
 	testData := []struct {
 		name     string
-		count    int
+		cells    int
 		expected int
 	}{
-		{"1 tetromino", 1, 2},      // 1 piece = 4 cells, sqrt(4) = 2
-		{"2 tetrominoes", 2, 3},    // 2 pieces = 8 cells, sqrt(8) ≈ 2.83, ceil = 3
-		{"4 tetrominoes", 4, 4},    // 4 pieces = 16 cells, sqrt(16) = 4
-		{"10 tetrominoes", 10, 7},  // 10 pieces = 40 cells, sqrt(40) ≈ 6.32, ceil = 7
-		{"64 tetrominoes", 64, 16}, // 64 pieces = 256 cells, sqrt(256) = 16
+		{"1 tetromino", 4, 2},          // 1 piece = 4 cells, sqrt(4) = 2
+		{"2 tetrominoes", 8, 3},        // 2 pieces = 8 cells, sqrt(8) ≈ 2.83, ceil = 3
+		{"4 tetrominoes", 16, 4},       // 4 pieces = 16 cells, sqrt(16) = 4
+		{"10 tetrominoes", 40, 7},      // 10 pieces = 40 cells, sqrt(40) ≈ 6.32, ceil = 7
+		{"64 tetrominoes", 256, 16},    // 64 pieces = 256 cells, sqrt(256) = 16
+		{"3 pentominoes", 15, 4},       // 3 pieces = 15 cells, sqrt(15) ≈ 3.87, ceil = 4
+		{"1 domino, 1 hexomino", 8, 3}, // 2 + 6 = 8 cells, sqrt(8) ≈ 2.83, ceil = 3
 	}
 
 	for _, test := range testData {
 		t.Run(test.name, func(t *testing.T) {
-			got := minimumBoardSize(test.count)
+			got := minimumBoardSize(test.cells)
 			if got != test.expected {
-				t.Errorf("minimumBoardSize(%d) = %d, want %d", test.count, got, test.expected)
+				t.Errorf("minimumBoardSize(%d) = %d, want %d", test.cells, got, test.expected)
 			}
 		})
 	}
@@ -33,71 +45,51 @@ func TestMaximumBoardSize(t *testing.T) {
 	testData := []struct {
 		name  string
 		count int
+		side  int
 		min   int
 	}{
-		{"1 tetromino", 1, 4},      // cells: 1*16 = 16, sqrt(16) = 4
-		{"2 tetrominoes", 2, 6},    // cells: 2*16 = 32, sqrt(32) ≈ 5.6568, 6
-		{"4 tetrominoes", 4, 8},    // cells: 4*16 = 64, sqrt(64) = 8
-		{"64 tetrominoes", 64, 32}, // cells: 64*16 = 1024, sqrt(1024) = 32
+		{"1 tetromino", 1, 4, 4},      // 1 box per row, 1 * 4 = 4
+		{"2 tetrominoes", 2, 4, 8},    // sqrt(2) ≈ 1.41, 2 boxes per row, 2 * 4 = 8
+		{"4 tetrominoes", 4, 4, 8},    // 2 boxes per row, 2 * 4 = 8
+		{"64 tetrominoes", 64, 4, 32}, // 8 boxes per row, 8 * 4 = 32
+		{"5 pentominoes", 5, 5, 15},   // sqrt(5) ≈ 2.24, 3 boxes per row, 3 * 5 = 15
 	}
 
 	for _, test := range testData {
 		t.Run(test.name, func(t *testing.T) {
-			got := maximumBoardSize(test.count)
+			got := maximumBoardSize(test.count, test.side)
 			if got != test.min {
-				t.Errorf("maximumBoardSize(%d) = %d, want at least %d", test.count, got, test.min)
+				t.Errorf("maximumBoardSize(%d, %d) = %d, want at least %d", test.count, test.side, got, test.min)
 			}
 		})
 	}
 }
 
-func TestSolveEmptyList(t *testing.T) {
-	board := tetris.NewBoard(4)
-
-	if !solve(&board, []tetris.Piece{}, nil) {
-		t.Fatal("expected solve to succeed with empty piece list")
+func TestBoardSizeBounds(t *testing.T) {
+	iPentomino := tetris.Piece{
+		Pos:    []tetris.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 3, Y: 0}, {X: 4, Y: 0}},
+		Width:  5,
+		Height: 1,
 	}
-}
+	domino := tetris.Piece{Pos: []tetris.Point{{X: 0, Y: 0}, {X: 0, Y: 1}}, Width: 1, Height: 2}
 
-func TestSolve(t *testing.T) {
 	testData := []struct {
 		name     string
-		expected bool
-		board    tetris.Board
-		piece    tetris.Piece
+		pieces   []tetris.Piece
+		min, max int
 	}{
-		{
-			name:     "Single piece",
-			expected: true,
-			board:    tetris.NewBoard(2),
-			piece: tetris.Piece{
-				Pos:    [4]tetris.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}},
-				Width:  2,
-				Height: 2,
-				ID:     'A',
-			},
-		},
-		{
-			name:     "Too small board",
-			expected: false,
-			board:    tetris.NewBoard(2),
-			piece: tetris.Piece{
-				Pos:    [4]tetris.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 3, Y: 0}},
-				Width:  4,
-				Height: 1,
-				ID:     'A',
-			},
-		},
+		{"Empty", nil, 0, 0},
+		{"Longest piece beats area", []tetris.Piece{iPentomino}, 5, 5},
+		{"Area beats longest piece", slices.Repeat([]tetris.Piece{domino}, 8), 4, 6},
+		{"Mixed sizes", []tetris.Piece{iPentomino, domino, domino}, 5, 10},
+		{"Clamped to board limit", slices.Repeat([]tetris.Piece{iPentomino}, 200), 32, tetris.MaxSize},
 	}
 
 	for _, test := range testData {
 		t.Run(test.name, func(t *testing.T) {
-			output := solve(&test.board, []tetris.Piece{test.piece}, nil)
-			if output != test.expected {
-				t.Fatalf(
-					"expected solve(board(%d), {piece(W: %d, H: %d)}) == '%v', got '%v'",
-					test.board.Size, test.piece.Width, test.piece.Height, test.expected, output,
-				)
+			minSize, maxSize := boardSizeBounds(test.pieces)
+			if minSize != test.min || maxSize != test.max {
+				t.Errorf("boardSizeBounds() = (%d, %d), want (%d, %d)", minSize, maxSize, test.min, test.max)
 			}
 		})
 	}
@@ -106,19 +98,601 @@ func TestSolve(t *testing.T) {
 func TestFindSmallestSquare(t *testing.T) {
 	// Create a simple 2x2 piece
 	piece := tetris.Piece{
-		Pos:    [4]tetris.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}},
+		Pos:    []tetris.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}},
 		Width:  2,
 		Height: 2,
-		ID:     'A',
+		ID:     0,
+	}
+
+	s, err := solver.New(solver.DefaultName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	board, err := FindSmallestSquare(context.Background(), []tetris.Piece{piece}, s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if board.Width < 2 {
+		t.Errorf("expected board size >= 2 for single 2x2 piece, got %d", board.Width)
+	}
+
+	if board.Width > 4 {
+		t.Errorf("expected board size <= 4 for single piece, got %d", board.Width)
+	}
+}
+
+func TestFindSmallestSquareExpected(t *testing.T) {
+	testData := []struct {
+		file     string
+		expected string
+	}{
+		{
+			"tests/samples/sample00-04",
+			"ABBBB.\nACCCEE\nAFFCEE\nA.FFGG\nHHHDDG\n.HDD.G\n",
+		},
+		{
+			"tests/good_examples/goodexample03-05",
+			"B.CCEAA\nBCCEEAA\nBDDEFFF\nBDD.HHF\nJGGHHII\nJJGKKKI\n.JG.K.I\n",
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.file, func(t *testing.T) {
+			expected, err := tetris.ParseBoard(strings.NewReader(test.expected))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			s, _ := solver.New(solver.DefaultName)
+			board, err := FindSmallestSquare(context.Background(), loadPieces(t, test.file), s)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !board.Equal(expected) {
+				t.Fatalf("expected:\n%s\ngot:\n%s", expected.ToString(), board.ToString())
+			}
+		})
+	}
+}
+
+// loadPieces parses and validates a tetromino file from the tests directory.
+func loadPieces(t *testing.T, path string) []tetris.Piece {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+
+	defer file.Close()
+	raws, err := ParseTetrominoStream(file, ParseOptions{})
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+
+	pieces, err := initTetrominoPieces(raws, 0)
+	if err != nil {
+		t.Fatalf("failed to init %s: %v", path, err)
+	}
+
+	return pieces
+}
+
+func TestFindSmallestSquareSolvers(t *testing.T) {
+	files := []string{
+		"tests/good_examples/goodexample01-09",
+		"tests/good_examples/goodexample03-05",
+		"tests/samples/sample00-04",
+		"tests/samples/hardsample-01",
+	}
+	// Input-order backtracking needs several seconds on the hard sample.
+	slow := map[string]string{"tests/samples/hardsample-01": "backtrack"}
+
+	for _, file := range files {
+		pieces := loadPieces(t, file)
+		expected := -1
+
+		for _, name := range solver.Names() {
+			if slow[file] == name {
+				continue
+			}
+
+			t.Run(file+"/"+name, func(t *testing.T) {
+				s, err := solver.New(name)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				board, err := FindSmallestSquare(context.Background(), pieces, s)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if expected == -1 {
+					expected = board.Width
+				} else if board.Width != expected {
+					t.Fatalf("expected size %d, got %d", expected, board.Width)
+				}
+
+				checkIdenticalOrder(t, board, pieces)
+			})
+		}
+	}
+}
+
+// checkIdenticalOrder fails the test unless the identical pieces on board are
+// labelled in reading order.
+func checkIdenticalOrder(t *testing.T, board tetris.Board, pieces []tetris.Piece) {
+	t.Helper()
+
+	cells := board.Cells()
+
+	for _, group := range tetris.IdenticalGroups(pieces) {
+		for k := 1; k < len(group); k++ {
+			previous, current := cells[pieces[group[k-1]].ID][0], cells[pieces[group[k]].ID][0]
+
+			if previous.Y > current.Y || (previous.Y == current.Y && previous.X > current.X) {
+				t.Fatalf("expected identical pieces %d and %d in reading order, got:\n%s",
+					group[k-1], group[k], board.ToString())
+			}
+		}
+	}
+}
+
+func TestFindSmallestSquareInterrupted(t *testing.T) {
+	pieces := loadPieces(t, "tests/samples/hardsample-01")
+
+	for _, name := range []string{"backtrack", "dlx", "hybrid"} {
+		t.Run(name, func(t *testing.T) {
+			s, err := solver.New(name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancel()
+			<-ctx.Done()
+
+			board, err := FindSmallestSquare(ctx, pieces, s)
+			if !errors.Is(err, ErrInterrupted) || !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected interrupted deadline error, got %v", err)
+			}
+
+			if minSize, _ := boardSizeBounds(pieces); board.Width != minSize {
+				t.Errorf("expected partial board of size %d, got %d", minSize, board.Width)
+			}
+		})
+	}
+
+	t.Run("partial result", func(t *testing.T) {
+		s, _ := solver.New("backtrack")
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		board, err := FindSmallestSquare(ctx, pieces, s)
+		if !errors.Is(err, ErrInterrupted) {
+			t.Fatalf("expected interrupted error, got %v", err)
+		}
+
+		empty := strings.Count(board.ToString(), ".")
+		if empty == board.Width*board.Height {
+			t.Fatal("expected partial board to hold some pieces")
+		}
+	})
+}
+
+func TestFindSmallestSquareStats(t *testing.T) {
+	// The 4 pieces fill 16 cells but need a 5×5 square, so two sizes are searched.
+	pieces := loadPieces(t, "tests/good_examples/goodexample01-09")
+
+	search := map[string]func() (tetris.Board, Stats, error){
+		"sequential": func() (tetris.Board, Stats, error) {
+			return FindSmallestSquareStats(context.Background(), pieces, solver.DLX{})
+		},
+		"parallel": func() (tetris.Board, Stats, error) {
+			factory := func() solver.Solver { return solver.DLX{} }
+			opts := ParallelOptions{Workers: 2, Sizes: 3, Deterministic: true}
+
+			return FindSmallestSquareParallelStats(context.Background(), pieces, factory, opts)
+		},
+	}
+
+	for name, run := range search {
+		t.Run(name, func(t *testing.T) {
+			board, stats, err := run()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(stats.Sizes) != 2 || stats.Sizes[0].Size != 4 || stats.Sizes[1].Size != board.Width {
+				t.Fatalf("expected sizes 4 and %d, got %+v", board.Width, stats.Sizes)
+			}
+
+			if infeasible := stats.Sizes[0]; infeasible.Solved || infeasible.Interrupted || infeasible.Strategy != "" {
+				t.Errorf("expected size 4 to be infeasible, got %+v", infeasible)
+			}
+
+			if solved := stats.Sizes[1]; !solved.Solved || solved.Strategy != "dlx" || solved.MaxDepth != len(pieces) {
+				t.Errorf("expected size 5 to be solved by dlx, got %+v", solved)
+			}
+
+			if total := stats.Total(); total.Nodes == 0 || total.Placements == 0 || total.Strategy != "dlx" {
+				t.Errorf("expected counted work, got %+v", total)
+			}
+		})
 	}
+}
+
+func TestFindSmallestSquareStatsInterrupted(t *testing.T) {
+	pieces := loadPieces(t, "tests/samples/hardsample-01")
 
-	board := FindSmallestSquare([]tetris.Piece{piece})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, stats, err := FindSmallestSquareStats(ctx, pieces, solver.DLX{})
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("expected ErrInterrupted, got %v", err)
+	}
 
-	if board.Size < 2 {
-		t.Errorf("expected board size >= 2 for single 2x2 piece, got %d", board.Size)
+	if len(stats.Sizes) != 1 || !stats.Sizes[0].Interrupted {
+		t.Errorf("expected one interrupted size, got %+v", stats.Sizes)
 	}
+}
+
+func TestFindSmallestSquareParallel(t *testing.T) {
+	testData := []struct {
+		file    string
+		solvers []string
+		trapped bool // The sorted phase of hybrid times out, so it restarts in input order
+	}{
+		{"tests/good_examples/goodexample01-09", []string{"sorted", "dlx", "hybrid"}, false},
+		{"tests/good_examples/goodexample03-05", []string{"sorted", "dlx", "hybrid"}, false},
+		{"tests/samples/sample00-04", []string{"sorted", "dlx", "hybrid"}, false},
+		{"tests/samples/hardsample-01", []string{"sorted", "dlx", "hybrid"}, false},
+		{"tests/samples/sample01-05", []string{"hybrid"}, true},
+	}
+
+	for _, test := range testData {
+		pieces := loadPieces(t, test.file)
+
+		for _, name := range test.solvers {
+			t.Run(test.file+"/"+name, func(t *testing.T) {
+				newSolver := func() solver.Solver {
+					s, _ := solver.New(name)
+					return s
+				}
+
+				sequential, stats, err := FindSmallestSquareStats(context.Background(), pieces, newSolver())
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if timedOut := stats.Total().HeuristicTimedOut; timedOut != test.trapped {
+					t.Fatalf("expected the sorted phase to time out: %v, got %v", test.trapped, timedOut)
+				}
+
+				opts := ParallelOptions{Workers: 4, Sizes: 3, Deterministic: true}
+				parallel, err := FindSmallestSquareParallel(context.Background(), pieces, newSolver, opts)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if !parallel.Equal(sequential) {
+					t.Fatalf("expected:\n%s\ngot:\n%s", sequential.ToString(), parallel.ToString())
+				}
+
+				opts.Deterministic = false
+				parallel, err = FindSmallestSquareParallel(context.Background(), pieces, newSolver, opts)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if parallel.Width != sequential.Width {
+					t.Fatalf("expected size %d, got %d", sequential.Width, parallel.Width)
+				}
+			})
+		}
+	}
+}
+
+func TestFindSmallestSquareParallelInterrupted(t *testing.T) {
+	pieces := loadPieces(t, "tests/samples/hardsample-01")
+	newSolver := func() solver.Solver { return solver.Backtracker{} }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	opts := ParallelOptions{Sizes: 2, Deterministic: true}
+	board, err := FindSmallestSquareParallel(ctx, pieces, newSolver, opts)
+	if !errors.Is(err, ErrInterrupted) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected interrupted deadline error, got %v", err)
+	}
+
+	if board.Filled() == 0 {
+		t.Error("expected partial board to hold some pieces")
+	}
+}
+
+func TestFindSmallestSquareRotation(t *testing.T) {
+	pieces, err := initTetrominoPieces(makeRaws(rawRows("#...", "#...", "##..", "...."), 4), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testData := []struct {
+		transforms tetris.Transform
+		expected   int
+	}{
+		{0, 6},
+		{tetris.Rotate, 4},
+	}
+
+	for _, test := range testData {
+		for i := range pieces {
+			pieces[i].Transforms = test.transforms
+		}
+
+		for _, name := range solver.Names() {
+			t.Run(fmt.Sprintf("transforms %d/%s", test.transforms, name), func(t *testing.T) {
+				s, _ := solver.New(name)
+				board, err := FindSmallestSquare(context.Background(), pieces, s)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if board.Width != test.expected {
+					t.Fatalf("expected size %d, got %d:\n%s", test.expected, board.Width, board.ToString())
+				}
+			})
+		}
+	}
+}
+
+func TestSquareSymmetryKeepsSizes(t *testing.T) {
+	files, err := filepath.Glob("tests/samples/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	examples, _ := filepath.Glob("tests/good_examples/*")
+	files = append(files, examples...)
+
+	// 9 I pieces fill a 6×6 square but cannot tile it, so 6×6 is searched in full.
+	nineI, err := initTetrominoPieces(makeRaws(rawRows("####", "....", "....", "...."), 9), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	puzzles := map[string][]tetris.Piece{"9 I pieces": nineI}
+	for _, file := range files {
+		puzzles[file] = loadPieces(t, file)
+	}
+
+	// Input-order backtracking needs seconds on the hard samples, as does the sorted
+	// order on the trap the hybrid solver falls back from.
+	slow := map[string][]string{
+		"tests/samples/hardsample-01": {"backtrack"},
+		"tests/samples/sample01-05":   {"backtrack", "sorted"},
+	}
+
+	for _, transforms := range []tetris.Transform{0, tetris.Rotate, tetris.Rotate | tetris.Mirror} {
+		for name, pieces := range puzzles {
+			pieces = slices.Clone(pieces)
+			for i := range pieces {
+				pieces[i].Transforms = transforms
+			}
+
+			// The first-empty-cell search places the first piece anywhere.
+			expected, err := FindSmallestSquare(context.Background(), pieces, solver.CellFirst{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, solverName := range []string{"backtrack", "sorted", "hybrid"} {
+				if slices.Contains(slow[name], solverName) {
+					continue
+				}
+
+				t.Run(fmt.Sprintf("transforms %d/%s/%s", transforms, name, solverName), func(t *testing.T) {
+					s, _ := solver.New(solverName)
+					board, err := FindSmallestSquare(context.Background(), pieces, s)
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+
+					if board.Width != expected.Width {
+						t.Fatalf("expected size %d, got %d:\n%s", expected.Width, board.Width, board.ToString())
+					}
+
+					newSolver := func() solver.Solver { s, _ := solver.New(solverName); return s }
+					opts := ParallelOptions{Workers: 2, Sizes: 2}
+
+					parallel, err := FindSmallestSquareParallel(context.Background(), pieces, newSolver, opts)
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+
+					if parallel.Width != expected.Width {
+						t.Fatalf("expected size %d in parallel, got %d:\n%s", expected.Width, parallel.Width, parallel.ToString())
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestRectangleCandidates(t *testing.T) {
+	pieces, err := readPieces(strings.NewReader("####\n\n####\n"), true, 0, ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testData := []struct {
+		name       string
+		transforms tetris.Transform
+		maxAspect  float64
+		expected   []rectangle
+	}{
+		{"Fixed", 0, 0, []rectangle{{4, 2}, {8, 1}, {9, 1}, {5, 2}, {10, 1}}},
+		{"Rotate", tetris.Rotate, 0, []rectangle{{4, 2}, {2, 4}, {8, 1}, {1, 8}, {9, 1}}},
+		{"Aspect limit", 0, 1.5, []rectangle{{4, 3}, {4, 4}, {5, 4}, {4, 5}, {6, 4}}},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			for i := range pieces {
+				pieces[i].Transforms = test.transforms
+			}
+
+			candidates := rectangleCandidates(pieces, test.maxAspect)
+			if len(candidates) < len(test.expected) {
+				t.Fatalf("expected at least %d candidates, got %v", len(test.expected), candidates)
+			}
+
+			if got := candidates[:len(test.expected)]; !slices.Equal(got, test.expected) {
+				t.Fatalf("expected %v first, got %v", test.expected, got)
+			}
+
+			// Candidates stop at the largest square searched by FindSmallestSquare.
+			if last := candidates[len(candidates)-1]; last.Width*last.Height > 64 {
+				t.Errorf("expected no candidate larger than 8×8, got %v", last)
+			}
+		})
+	}
+}
+
+func TestFindSmallestRectangle(t *testing.T) {
+	testData := []struct {
+		file      string
+		maxAspect float64
+		expected  rectangle
+	}{
+		{"tests/good_examples/goodexample00-00", 0, rectangle{2, 2}},
+		{"tests/good_examples/goodexample01-09", 0, rectangle{5, 4}},
+		{"tests/samples/sample00-04", 0, rectangle{7, 5}},
+		{"tests/samples/sample00-04", 1, rectangle{6, 6}},
+	}
+
+	for _, name := range solver.Names() {
+		for _, test := range testData {
+			t.Run(fmt.Sprintf("%s/%s/%g", name, test.file, test.maxAspect), func(t *testing.T) {
+				s, _ := solver.New(name)
+				pieces := loadPieces(t, test.file)
+
+				board, err := FindSmallestRectangle(context.Background(), pieces, s, test.maxAspect)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if got := (rectangle{board.Width, board.Height}); got != test.expected {
+					t.Fatalf("expected %v, got %v:\n%s", test.expected, got, board.ToString())
+				}
+
+				if board.Filled() != pieceCells(pieces) {
+					t.Fatalf("expected every piece on the board, got:\n%s", board.ToString())
+				}
+			})
+		}
+	}
+}
+
+func TestFindSmallestSquarePolyominoes(t *testing.T) {
+	input := "#####\n\n#####\n\n#####\n\n##\n##\n\n##\n##\n\n#\n#\n"
+	pieces, err := readPieces(strings.NewReader(input), true, 0, ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range solver.Names() {
+		t.Run(name, func(t *testing.T) {
+			s, _ := solver.New(name)
+			board, err := FindSmallestSquare(context.Background(), pieces, s)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// 3 pentominoes, 2 tetrominoes and a domino exactly fill a 5×5 square.
+			if board.Width != 5 || board.Filled() != 25 {
+				t.Fatalf("expected a full 5×5 board, got:\n%s", board.ToString())
+			}
+		})
+	}
+}
+
+func TestFitContainer(t *testing.T) {
+	pieces := loadPieces(t, "tests/good_examples/goodexample00-00")
+	testData := []struct {
+		name     string
+		shape    string
+		expected error
+	}{
+		{"Exact fit", "###..\n###..\n", nil},
+		{"Room to spare", "......\n......\n", nil},
+		{"Wrong shape", "#..\n..#\n", ErrNoFit},
+		{"Too few cells", "#.\n..\n", ErrNoFit},
+	}
+
+	for _, name := range solver.Names() {
+		for _, test := range testData {
+			t.Run(name+"/"+test.name, func(t *testing.T) {
+				container, err := ParseContainer(bufio.NewScanner(strings.NewReader(test.shape)))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				s, _ := solver.New(name)
+				board, err := FitContainer(context.Background(), pieces, container, s)
+				if !errors.Is(err, test.expected) {
+					t.Fatalf("expected error %v, got %v", test.expected, err)
+				}
+
+				if err != nil {
+					return
+				}
+
+				if board.Filled() != 4 || container.Filled() != 0 {
+					t.Fatalf("expected 4 filled cells in a fresh board, got:\n%s", board.ToString())
+				}
+
+				for y := range container.Height {
+					for x := range container.Width {
+						if board.Blocked(x, y) != container.Blocked(x, y) {
+							t.Fatalf("expected blocked cells to be kept, got:\n%s", board.ToString())
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkFindSmallestSquare(b *testing.B) {
+	// Plain backtracking takes seconds on the hard puzzles, so it is left out.
+	solvers := []string{solver.DefaultName, "sorted", "cell"}
+
+	for _, p := range benchCorpus {
+		pieces, err := loadBenchPuzzle(p)
+		if err != nil {
+			b.Fatalf("failed to load %s: %v", p.Name, err)
+		}
+
+		for _, name := range solvers {
+			b.Run(name+"/"+p.Grade+"/"+p.Name, func(b *testing.B) {
+				var counter solver.Counter
+
+				ctx := solver.WithCounter(context.Background(), &counter)
+
+				for b.Loop() {
+					s, _ := solver.New(name)
+					if _, err := FindSmallestSquare(ctx, pieces, s); err != nil {
+						b.Fatalf("unexpected error: %v", err)
+					}
+				}
 
-	if board.Size > 4 {
-		t.Errorf("expected board size <= 4 for single piece, got %d", board.Size)
+				b.ReportMetric(float64(counter.Nodes())/float64(b.N), "nodes/op")
+			})
+		}
 	}
 }
@@ -0,0 +1,408 @@
+// Package solver contains the backtracking strategies.
+package solver
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"tetris-optimizer/tetris"
+)
+
+// DefaultHybridTimeout bounds the sorted phase of the Hybrid strategy.
+const DefaultHybridTimeout = 500 * time.Millisecond
+
+// searchState tracks cancellation and the deepest partial placement of a search.
+type searchState struct {
+	done      <-chan struct{}
+	cancelled bool
+	ops       int          // Nodes visited, also throttling cancellation checks
+	placed    int          // Number of pieces currently on the board
+	best      tetris.Board // Snapshot of the deepest partial placement
+	bestCount int
+
+	dead   *deadRegions    // Pockets no piece can fill, checked after each placement
+	sym    symmetry        // Order imposed on identical pieces
+	square *squareSymmetry // Region of the first placement on an empty square, if any
+
+	placements, backtracks, pruned int64
+}
+
+// newSearchState returns the state for placing pieces, given in search order, on board
+// that stops once ctx is done.
+func newSearchState(ctx context.Context, board *tetris.Board, pieces []tetris.Piece) *searchState {
+	return &searchState{
+		done:   ctx.Done(),
+		best:   board.Clone(),
+		dead:   newDeadRegions(board, pieces),
+		sym:    newSymmetry(pieces),
+		square: newSquareSymmetry(board, pieces),
+	}
+}
+
+// interrupted reports whether the search should unwind.
+func (s *searchState) interrupted() bool {
+	// OPTIMIZATION: Only poll the context every 1024 iterations.
+	// Even a non-blocking channel receive is costly on the hot path.
+	s.ops++
+	if s.ops&1023 == 0 && !s.cancelled {
+		select {
+		case <-s.done:
+			s.cancelled = true
+		default:
+		}
+	}
+
+	return s.cancelled
+}
+
+// record snapshots the board if it holds more pieces than any earlier state.
+func (s *searchState) record(board *tetris.Board) {
+	if s.placed > s.bestCount {
+		s.best = board.Clone()
+		s.bestCount = s.placed
+	}
+}
+
+// stats returns the stats of the search, crediting strategy if it placed every piece.
+func (s *searchState) stats(strategy string, solved bool) SearchStats {
+	stats := SearchStats{
+		Nodes:      int64(s.ops),
+		Placements: s.placements,
+		Backtracks: s.backtracks,
+		Pruned:     s.pruned,
+		MaxDepth:   s.bestCount,
+	}
+
+	if solved {
+		stats.Strategy = strategy
+	}
+
+	return stats
+}
+
+// orientations returns the allowed orientations of each piece, in piece order.
+// Identical pieces take the orientations of the first of them, in the same order,
+// so that their placements rank alike.
+func orientations(pieces []tetris.Piece) [][]tetris.Piece {
+	out := make([][]tetris.Piece, len(pieces))
+
+	for _, group := range tetris.IdenticalGroups(pieces) {
+		first := pieces[group[0]].Orientations()
+
+		for _, i := range group {
+			out[i] = make([]tetris.Piece, len(first))
+
+			for j, o := range first {
+				o.ID = pieces[i].ID
+				out[i][j] = o
+			}
+		}
+	}
+
+	return out
+}
+
+// solve recursively places pieces, given as their allowed orientations, using backtracking.
+// A nil state disables cancellation, partial result tracking, dead-region pruning
+// and symmetry breaking.
+func solve(board *tetris.Board, pieces [][]tetris.Piece, state *searchState) bool {
+	if state != nil && state.interrupted() {
+		return false
+	}
+
+	if len(pieces) == 0 {
+		return true
+	}
+
+	remaining := pieces[1:]
+
+	depth, floor := 0, -1
+	var square *squareSymmetry
+
+	if state != nil {
+		depth = len(state.sym.after) - len(pieces)
+		floor = state.sym.floor(depth)
+
+		if depth == 0 {
+			square = state.square
+		}
+	}
+
+	// Try all valid positions of every orientation of the current piece
+	for o, current := range pieces[0] {
+		for y := 0; y <= board.Height-current.Height; y++ {
+			for x := 0; x <= board.Width-current.Width; x++ {
+				// OPTIMIZATION: Identical pieces are placed in increasing rank only,
+				// so each of their permutations is not searched again.
+				r := rank(board, o, x, y)
+				if r <= floor || !board.CanPlace(current, x, y) {
+					continue
+				}
+
+				// OPTIMIZATION: A first placement that a symmetry of the square maps
+				// to a lower rank is searched there already.
+				if square != nil && !square.canonical(board, o, x, y, current) {
+					continue
+				}
+
+				board.Place(current, x, y)
+
+				pruned, wasted := false, 0
+				if state != nil {
+					state.sym.ranks[depth] = r
+					state.placed++
+					state.placements++
+					state.record(board)
+
+					// OPTIMIZATION: Give up on a placement as soon as it closes off
+					// more cells than the pieces can leave empty.
+					wasted = state.dead.wasted
+					pruned = !state.dead.place(board, current, x, y)
+				}
+
+				if !pruned && solve(board, remaining, state) {
+					return true
+				}
+
+				// OPTIMIZATION: If the search was cancelled deeper in the recursion,
+				// break this loop immediately to unwind the stack fast.
+				if state != nil && state.cancelled {
+					return false
+				}
+
+				board.Remove(current, x, y)
+				if state != nil {
+					state.placed--
+					state.dead.wasted = wasted
+
+					if pruned {
+						state.pruned++
+					} else {
+						state.backtracks++
+					}
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// sortWidestFirst returns a copy of pieces ordered by their largest dimension, descending.
+func sortWidestFirst(pieces []tetris.Piece) []tetris.Piece {
+	// OPTIMIZATION: Sort pieces to place the largest/hardest ones first.
+	// This drastically reduces the branching factor of the recursion in some cases.
+	// WARNING: This will also cripple performance of certain cases.
+	sortedPieces := make([]tetris.Piece, len(pieces))
+
+	copy(sortedPieces, pieces)
+	slices.SortFunc(sortedPieces, func(a, b tetris.Piece) int {
+		maxA := max(a.Width, a.Height)
+		maxB := max(b.Width, b.Height)
+		// The subtraction is reversed to cause items to be sorted in descending order.
+		return maxB - maxA
+	})
+
+	return sortedPieces
+}
+
+// Backtracker is a depth-first search placing pieces one at a time at every position.
+type Backtracker struct {
+	Sorted bool // Place the widest pieces first instead of using input order
+
+	root *splitRoot // Placement made by Split above this branch, if any
+}
+
+// splitRoot is the first placement of a search, made by Split.
+type splitRoot struct {
+	piece tetris.Piece
+	rank  int
+}
+
+// Solve implements Solver.
+func (bt Backtracker) Solve(ctx context.Context, board *tetris.Board, pieces []tetris.Piece) bool {
+	if !parityFeasible(board, pieces) {
+		return false
+	}
+
+	state, solved := bt.search(ctx, board, pieces)
+	record(ctx, state.stats(bt.name(), solved))
+
+	if solved {
+		return true
+	}
+
+	if state.cancelled {
+		*board = state.best
+	}
+
+	return false
+}
+
+// search places pieces on board, leaving the stats and the partial placement
+// of a cancelled search to the caller.
+func (bt Backtracker) search(ctx context.Context, board *tetris.Board, pieces []tetris.Piece) (*searchState, bool) {
+	if bt.Sorted {
+		pieces = sortWidestFirst(pieces)
+	}
+
+	state := newSearchState(ctx, board, pieces)
+	shapes := orientations(pieces)
+
+	// A branch goes on with the ranks of the search it was split from.
+	if bt.root != nil {
+		all := append([]tetris.Piece{bt.root.piece}, pieces...)
+		state.sym = newSymmetry(all)
+		state.sym.ranks[0] = bt.root.rank
+		shapes = orientations(all)[1:]
+	}
+
+	return state, state.dead.feasible() && solve(board, shapes, state)
+}
+
+// name returns the name the strategy is registered under.
+func (bt Backtracker) name() string {
+	if bt.Sorted {
+		return "sorted"
+	}
+
+	return "backtrack"
+}
+
+// Hybrid runs the sorted backtracker under a short timeout and falls back to
+// input order when the heuristic turns out to be a trap. It is safe for
+// concurrent use, so the sizes and branches of a parallel search share one.
+type Hybrid struct {
+	Timeout time.Duration
+	trapped atomic.Bool // Set once the sorted phase has timed out
+}
+
+// Solve implements Solver.
+func (h *Hybrid) Solve(ctx context.Context, board *tetris.Board, pieces []tetris.Piece) bool {
+	// A board ruled out by parity must not count as a trap for the heuristic.
+	if !parityFeasible(board, pieces) {
+		return false
+	}
+
+	if !h.trapped.Load() {
+		sortedCtx, cancel := context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+
+		if solved, settled := h.sorted(ctx, sortedCtx, board, pieces, Backtracker{Sorted: true}); settled {
+			return solved
+		}
+	}
+
+	// Fallback (Original Input Order)
+	return Backtracker{}.Solve(ctx, board, pieces)
+}
+
+// sorted runs the sorted phase with bt until sortedCtx is done. It reports
+// whether the board was solved, and whether the size is settled: solved, ruled
+// out, or given up by the caller. Otherwise the heuristic timed out.
+func (h *Hybrid) sorted(
+	ctx, sortedCtx context.Context, board *tetris.Board, pieces []tetris.Piece, bt Backtracker,
+) (solved, settled bool) {
+	scratch := board.Clone()
+	state, found := bt.search(sortedCtx, &scratch, pieces)
+	stats := state.stats("sorted", found)
+
+	// The caller gave up, not just the heuristic.
+	if state.cancelled && ctx.Err() != nil {
+		record(ctx, stats)
+		*board = state.best
+		return false, true
+	}
+
+	stats.HeuristicTimedOut = state.cancelled
+	record(ctx, stats)
+
+	if found {
+		*board = scratch
+		return true, true
+	}
+
+	if !state.cancelled {
+		return false, true
+	}
+
+	// TIMEOUT DETECTED: The sorting heuristic is a trap for this puzzle.
+	// Disable it for all larger board sizes to avoid wasting time on every size.
+	h.trapped.Store(true)
+
+	return false, false
+}
+
+// Split implements Splitter. Until the sorted phase has timed out, the branches
+// are those of the sorted search, sharing one deadline. A branch that reaches
+// it is abandoned, so that the board is split again in input order and the
+// search restarts from the top as Solve does.
+func (h *Hybrid) Split(board *tetris.Board, pieces []tetris.Piece) []Branch {
+	if h.trapped.Load() {
+		return Backtracker{}.Split(board, pieces)
+	}
+
+	deadline := time.Now().Add(h.Timeout)
+	branches := Backtracker{Sorted: true}.Split(board, pieces)
+
+	for i := range branches {
+		root := branches[i].Solver.(Backtracker).root
+		branches[i].Solver = &hybridBranch{hybrid: h, root: root, deadline: deadline}
+	}
+
+	return branches
+}
+
+// hybridBranch solves a branch of Hybrid.Split in sorted order.
+type hybridBranch struct {
+	hybrid    *Hybrid
+	root      *splitRoot
+	deadline  time.Time // End of the sorted phase, shared by every branch of the board
+	abandoned bool      // The sorted phase timed out before settling the branch
+}
+
+// Solve implements Solver, given the pieces of the branch in sorted order.
+func (b *hybridBranch) Solve(ctx context.Context, board *tetris.Board, pieces []tetris.Piece) bool {
+	sortedCtx, cancel := context.WithDeadline(ctx, b.deadline)
+	defer cancel()
+
+	solved, settled := b.hybrid.sorted(ctx, sortedCtx, board, pieces, Backtracker{root: b.root})
+	b.abandoned = !settled
+
+	return solved
+}
+
+// Abandoned implements Abandoner.
+func (b *hybridBranch) Abandoned() bool {
+	return b.abandoned
+}
+
+// Split implements Splitter by placing the first piece at each position, in each orientation.
+func (bt Backtracker) Split(board *tetris.Board, pieces []tetris.Piece) []Branch {
+	if bt.Sorted {
+		pieces = sortWidestFirst(pieces)
+	}
+
+	var branches []Branch
+
+	square := newSquareSymmetry(board, pieces)
+
+	for o, first := range pieces[0].Orientations() {
+		for y := 0; y <= board.Height-first.Height; y++ {
+			for x := 0; x <= board.Width-first.Width; x++ {
+				if !board.CanPlace(first, x, y) || (square != nil && !square.canonical(board, o, x, y, first)) {
+					continue
+				}
+
+				root := &splitRoot{piece: pieces[0], rank: rank(board, o, x, y)}
+				branch := Branch{Board: board.Clone(), Pieces: pieces[1:], Solver: Backtracker{root: root}}
+				branch.Board.Place(first, x, y)
+				branches = append(branches, branch)
+			}
+		}
+	}
+
+	return branches
+}
@@ -0,0 +1,145 @@
+package solver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"tetris-optimizer/tetris"
+)
+
+func TestSolveEmptyList(t *testing.T) {
+	board := tetris.NewBoard(4)
+
+	if !solve(&board, [][]tetris.Piece{}, nil) {
+		t.Fatal("expected solve to succeed with empty piece list")
+	}
+}
+
+func TestSolve(t *testing.T) {
+	testData := []struct {
+		name     string
+		expected bool
+		board    tetris.Board
+		piece    tetris.Piece
+	}{
+		{
+			name:     "Single piece",
+			expected: true,
+			board:    tetris.NewBoard(2),
+			piece: tetris.Piece{
+				Pos:    []tetris.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}},
+				Width:  2,
+				Height: 2,
+				ID:     0,
+			},
+		},
+		{
+			name:     "Too small board",
+			expected: false,
+			board:    tetris.NewBoard(2),
+			piece: tetris.Piece{
+				Pos:    []tetris.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 3, Y: 0}},
+				Width:  4,
+				Height: 1,
+				ID:     0,
+			},
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			output := solve(&test.board, [][]tetris.Piece{{test.piece}}, nil)
+			if output != test.expected {
+				t.Fatalf(
+					"expected solve(board(%d×%d), {piece(W: %d, H: %d)}) == '%v', got '%v'",
+					test.board.Width, test.board.Height, test.piece.Width, test.piece.Height, test.expected, output,
+				)
+			}
+		})
+	}
+}
+
+func TestSortWidestFirst(t *testing.T) {
+	pieces := []tetris.Piece{
+		{Width: 2, Height: 2, ID: 0},
+		{Width: 4, Height: 1, ID: 1},
+		{Width: 2, Height: 3, ID: 2},
+	}
+
+	sorted := sortWidestFirst(pieces)
+	expected := []int{1, 2, 0}
+
+	for i, p := range sorted {
+		if p.ID != expected[i] {
+			t.Fatalf("expected order %v, got piece %d at %d", expected, p.ID, i)
+		}
+	}
+
+	if pieces[0].ID != 0 {
+		t.Fatal("expected input slice to be left unsorted")
+	}
+}
+
+// unfillableSquare returns 25 I pieces, which exactly cover the area of a 10×10
+// square but cannot tile it, so neither the parity check nor dead-region pruning
+// settles the board. Proving so takes far longer than the test deadlines.
+func unfillableSquare() []tetris.Piece {
+	pieces := make([]tetris.Piece, 25)
+	for i := range pieces {
+		pieces[i] = iPiece
+		pieces[i].ID = i
+	}
+
+	return pieces
+}
+
+func TestBacktrackerDeadline(t *testing.T) {
+	pieces := unfillableSquare()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	board := tetris.NewBoard(10)
+	start := time.Now()
+
+	if (Backtracker{}).Solve(ctx, &board, pieces) {
+		t.Fatal("expected solve to fail")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected deadline to stop the search, took %v", elapsed)
+	}
+
+	// The best partial placement packs most of the pieces.
+	if filled := 100 - strings.Count(board.ToString(), "."); filled < 50 {
+		t.Errorf("expected a mostly full partial board, got %d filled cells:\n%s", filled, board.ToString())
+	}
+}
+
+func BenchmarkSolve(b *testing.B) {
+	pieces := []tetris.Piece{tPiece, sPiece, lPiece, iPiece, tPiece, lPiece}
+	for i := range pieces {
+		pieces[i].ID = i
+	}
+
+	shapes := orientations(pieces)
+
+	for b.Loop() {
+		board := tetris.NewBoard(5)
+		solve(&board, shapes, nil)
+	}
+}
+
+func BenchmarkCellFirst(b *testing.B) {
+	pieces := []tetris.Piece{tPiece, sPiece, lPiece, iPiece, tPiece, lPiece}
+	for i := range pieces {
+		pieces[i].ID = i
+	}
+
+	for b.Loop() {
+		board := tetris.NewBoard(5)
+		CellFirst{}.Solve(context.Background(), &board, pieces)
+	}
+}
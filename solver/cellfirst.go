@@ -0,0 +1,144 @@
+// Package solver contains the first-empty-cell backtracking strategy.
+package solver
+
+import (
+	"context"
+
+	"tetris-optimizer/tetris"
+)
+
+// CellFirst fills the board in reading order. Each step covers the first
+// empty cell with a piece that has a block there, or leaves the cell empty for
+// good while the board still has cells to spare.
+//
+// Placing pieces one after another at every position reaches the same board
+// once for each order in which its pieces can be placed. Here a board is only
+// built from the top-left down, so each one is reached once per way of
+// assigning pieces to its cells.
+type CellFirst struct{}
+
+// anchored is an orientation of a piece with its first block in reading order,
+// which is the block placed on the cell being filled.
+type anchored struct {
+	piece  tetris.Piece
+	dx, dy int
+}
+
+// anchor returns orientation o with its first block in reading order.
+func anchor(o tetris.Piece) anchored {
+	first := o.Pos[0]
+
+	for _, b := range o.Pos[1:] {
+		if b.Y < first.Y || (b.Y == first.Y && b.X < first.X) {
+			first = b
+		}
+	}
+
+	return anchored{piece: o, dx: first.X, dy: first.Y}
+}
+
+// cellSearch is the state of a CellFirst search.
+type cellSearch struct {
+	board  *tetris.Board
+	shapes [][]anchored // Orientations of each piece, in piece order
+	used   []bool
+	left   int // Pieces not on the board yet
+	state  *searchState
+}
+
+// Solve implements Solver.
+func (CellFirst) Solve(ctx context.Context, board *tetris.Board, pieces []tetris.Piece) bool {
+	if !parityFeasible(board, pieces) {
+		return false
+	}
+
+	c := &cellSearch{
+		board:  board,
+		shapes: make([][]anchored, len(pieces)),
+		used:   make([]bool, len(pieces)),
+		left:   len(pieces),
+		state:  newSearchState(ctx, board, pieces),
+	}
+
+	for i, p := range pieces {
+		for _, o := range p.Orientations() {
+			c.shapes[i] = append(c.shapes[i], anchor(o))
+		}
+	}
+
+	// Pockets already on the board are left empty as the search reaches them.
+	solved := c.state.dead.feasible() && c.fill(0, c.state.dead.slack)
+	record(ctx, c.state.stats("cell", solved))
+
+	if solved {
+		return true
+	}
+
+	if c.state.cancelled {
+		*board = c.state.best
+	}
+
+	return false
+}
+
+// fill covers the first empty cell at or after index from in reading order,
+// with at most slack more cells left empty.
+func (c *cellSearch) fill(from, slack int) bool {
+	if c.state.interrupted() {
+		return false
+	}
+
+	if c.left == 0 {
+		return true
+	}
+
+	width, cells := c.board.Width, c.board.Width*c.board.Height
+	for from < cells && !c.board.Empty(from%width, from/width) {
+		from++
+	}
+
+	if from == cells {
+		return false
+	}
+
+	x, y := from%width, from/width
+
+	for i, orientations := range c.shapes {
+		// Identical pieces are placed in piece order, the first unused one standing
+		// for all of them.
+		if c.used[i] || (c.state.sym.after[i] >= 0 && !c.used[c.state.sym.after[i]]) {
+			continue
+		}
+
+		for _, o := range orientations {
+			originX, originY := x-o.dx, y-o.dy
+			if originX < 0 || originY < 0 || !c.board.CanPlace(o.piece, originX, originY) {
+				continue
+			}
+
+			c.board.Place(o.piece, originX, originY)
+			c.used[i] = true
+			c.left--
+			c.state.placed++
+			c.state.placements++
+			c.state.record(c.board)
+
+			if c.fill(from+1, slack) {
+				return true
+			}
+
+			if c.state.cancelled {
+				return false
+			}
+
+			c.board.Remove(o.piece, originX, originY)
+			c.used[i] = false
+			c.left++
+			c.state.placed--
+			c.state.backtracks++
+		}
+	}
+
+	// No piece covers the cell, so it stays empty if the board has room to spare.
+	return slack > 0 && c.fill(from+1, slack-1)
+}
@@ -0,0 +1,106 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tetris-optimizer/tetris"
+)
+
+func TestAnchor(t *testing.T) {
+	testData := []struct {
+		name   string
+		piece  tetris.Piece
+		dx, dy int
+	}{
+		{"I", iPiece, 0, 0},
+		{"T", tPiece, 0, 0},
+		{"S", sPiece, 1, 0},
+		{"Blocks out of order", tetris.Piece{Pos: []tetris.Point{{X: 0, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 0}}}, 1, 0},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			if a := anchor(test.piece); a.dx != test.dx || a.dy != test.dy {
+				t.Errorf("expected the anchor at (%d, %d), got (%d, %d)", test.dx, test.dy, a.dx, a.dy)
+			}
+		})
+	}
+}
+
+func TestCellFirstMatchesDLX(t *testing.T) {
+	testData := []struct {
+		name   string
+		pieces []tetris.Piece
+		size   uint
+	}{
+		{"Exact fit", []tetris.Piece{iPiece, iPiece, iPiece, iPiece}, 4},
+		{"Room to spare", []tetris.Piece{tPiece, sPiece, lPiece, iPiece, tPiece, lPiece}, 6},
+		{"Cells left empty in the middle", []tetris.Piece{sPiece, sPiece}, 4},
+		{"Too small", []tetris.Piece{tPiece, sPiece, lPiece, iPiece, tPiece, lPiece}, 5},
+		{"Shape does not fit", []tetris.Piece{iPiece, lPiece}, 3},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			pieces := make([]tetris.Piece, len(test.pieces))
+			for i, p := range test.pieces {
+				pieces[i] = p
+				pieces[i].ID = i
+			}
+
+			expected := tetris.NewBoard(test.size)
+			solvable := DLX{}.Solve(context.Background(), &expected, pieces)
+
+			board := tetris.NewBoard(test.size)
+			if solved := (CellFirst{}).Solve(context.Background(), &board, pieces); solved != solvable {
+				t.Fatalf("expected Solve() == %v, got %v", solvable, solved)
+			}
+
+			cells := board.Cells()
+			if !solvable {
+				if len(cells) != 0 {
+					t.Errorf("expected the board to be left empty, got:\n%s", board.ToString())
+				}
+
+				return
+			}
+
+			for i := range pieces {
+				if len(cells[i]) != 4 {
+					t.Errorf("expected piece %d on the board, got:\n%s", i, board.ToString())
+				}
+			}
+		})
+	}
+}
+
+func TestCellFirstDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Filling from one corner settles the fixed I pieces of unfillableSquare at
+	// once, so the pieces may turn here, on a square that still has no tiling.
+	pieces := make([]tetris.Piece, 49)
+	for i := range pieces {
+		pieces[i] = iPiece
+		pieces[i].ID = i
+		pieces[i].Transforms = tetris.Rotate
+	}
+
+	board := tetris.NewBoard(14)
+	start := time.Now()
+
+	if (CellFirst{}).Solve(ctx, &board, pieces) {
+		t.Fatal("expected solve to fail")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected deadline to stop the search, took %v", elapsed)
+	}
+
+	if board.Filled() == 0 {
+		t.Error("expected a partial placement on the cancelled board")
+	}
+}
@@ -0,0 +1,79 @@
+// Package solver contains the counters used to measure searches.
+package solver
+
+import (
+	"cmp"
+	"context"
+	"sync"
+)
+
+// SearchStats describes the work of one or more searches.
+type SearchStats struct {
+	Nodes      int64 // Recursive calls of the search
+	Placements int64 // Pieces placed, or exact-cover rows chosen
+	Backtracks int64 // Placements undone after the search below them failed
+	Pruned     int64 // Placements undone at once for closing off too many dead cells
+	MaxDepth   int   // Most pieces placed at once by a single search
+
+	// Strategy is the registered name of the strategy that placed every piece,
+	// empty if none did.
+	Strategy string
+
+	// HeuristicTimedOut is set when the sorted phase of Hybrid gave up on
+	// its timeout and fell back to input order.
+	HeuristicTimedOut bool
+}
+
+// Add merges other into s. Counts are summed, MaxDepth is the larger one, and
+// Strategy is taken from other unless it is empty.
+func (s *SearchStats) Add(other SearchStats) {
+	s.Nodes += other.Nodes
+	s.Placements += other.Placements
+	s.Backtracks += other.Backtracks
+	s.Pruned += other.Pruned
+	s.MaxDepth = max(s.MaxDepth, other.MaxDepth)
+	s.Strategy = cmp.Or(other.Strategy, s.Strategy)
+	s.HeuristicTimedOut = s.HeuristicTimedOut || other.HeuristicTimedOut
+}
+
+// Counter accumulates the stats of the searches run under a context. It is
+// safe for concurrent use, so parallel branches may share one. A Counter added
+// under a context that already has one passes its stats on to it as well.
+type Counter struct {
+	mu     sync.Mutex
+	stats  SearchStats
+	parent *Counter
+}
+
+// Nodes returns the number of nodes counted so far.
+func (c *Counter) Nodes() int64 {
+	return c.Stats().Nodes
+}
+
+// Stats returns the stats counted so far.
+func (c *Counter) Stats() SearchStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
+
+// counterKey is the context key of the Counter.
+type counterKey struct{}
+
+// WithCounter returns a context under which searches add their stats to c.
+func WithCounter(ctx context.Context, c *Counter) context.Context {
+	c.parent, _ = ctx.Value(counterKey{}).(*Counter)
+	return context.WithValue(ctx, counterKey{}, c)
+}
+
+// record adds the stats of a finished search to the Counters of ctx, if any.
+func record(ctx context.Context, stats SearchStats) {
+	c, _ := ctx.Value(counterKey{}).(*Counter)
+
+	for ; c != nil; c = c.parent {
+		c.mu.Lock()
+		c.stats.Add(stats)
+		c.mu.Unlock()
+	}
+}
@@ -0,0 +1,114 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tetris-optimizer/tetris"
+)
+
+func TestCounter(t *testing.T) {
+	pieces := []tetris.Piece{tPiece, sPiece, lPiece, iPiece, tPiece, lPiece}
+	for i := range pieces {
+		pieces[i].ID = i
+	}
+
+	testData := []struct {
+		name          string
+		solver        Solver
+		strategy      string // Strategy credited with the solution
+		deterministic bool
+	}{
+		{"backtrack", Backtracker{}, "backtrack", true},
+		{"sorted", Backtracker{Sorted: true}, "sorted", true},
+		{"hybrid", &Hybrid{Timeout: DefaultHybridTimeout}, "sorted", true},
+		{"dlx", DLX{}, "dlx", true},
+		{"cell", CellFirst{}, "cell", true},
+		{"parallel", Parallel{Inner: Backtracker{}, Workers: 2}, "backtrack", false},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			var first, second Counter
+
+			for _, counter := range []*Counter{&first, &second} {
+				board := tetris.NewBoard(6)
+				test.solver.Solve(WithCounter(context.Background(), counter), &board, pieces)
+			}
+
+			stats := first.Stats()
+			if stats.Nodes == 0 || stats.Placements == 0 {
+				t.Fatalf("expected nodes and placements to be counted, got %+v", stats)
+			}
+
+			if stats.Strategy != test.strategy || stats.MaxDepth != len(pieces) {
+				t.Errorf("expected %s to place all %d pieces, got %+v", test.strategy, len(pieces), stats)
+			}
+
+			if test.deterministic && first.Nodes() != second.Nodes() {
+				t.Errorf("expected the same count twice, got %d and %d", first.Nodes(), second.Nodes())
+			}
+		})
+	}
+}
+
+func TestCounterParityPruned(t *testing.T) {
+	// 9 pieces with a single T never fill a 6×6 square, so no search runs.
+	pieces := make([]tetris.Piece, 9)
+	for i := range pieces {
+		pieces[i] = lPiece
+		pieces[i].ID = i
+	}
+
+	pieces[0] = tPiece
+
+	var counter Counter
+
+	board := tetris.NewBoard(6)
+	if (Backtracker{}).Solve(WithCounter(context.Background(), &counter), &board, pieces) {
+		t.Fatal("expected solve to fail")
+	}
+
+	if counter.Nodes() != 0 {
+		t.Errorf("expected no nodes, got %d", counter.Nodes())
+	}
+}
+
+func TestCounterNested(t *testing.T) {
+	pieces := []tetris.Piece{tPiece, sPiece, lPiece}
+	for i := range pieces {
+		pieces[i].ID = i
+	}
+
+	var outer, first, second Counter
+
+	ctx := WithCounter(context.Background(), &outer)
+
+	for _, counter := range []*Counter{&first, &second} {
+		board := tetris.NewBoard(4)
+		DLX{}.Solve(WithCounter(ctx, counter), &board, pieces)
+	}
+
+	if first.Nodes() == 0 || outer.Nodes() != first.Nodes()+second.Nodes() {
+		t.Errorf("expected the outer counter to sum %d and %d, got %d", first.Nodes(), second.Nodes(), outer.Nodes())
+	}
+}
+
+func TestCounterHeuristicTimedOut(t *testing.T) {
+	pieces := unfillableSquare()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var counter Counter
+
+	board := tetris.NewBoard(10)
+	if (&Hybrid{Timeout: 20 * time.Millisecond}).Solve(WithCounter(ctx, &counter), &board, pieces) {
+		t.Fatal("expected solve to fail")
+	}
+
+	if stats := counter.Stats(); !stats.HeuristicTimedOut || stats.Strategy != "" {
+		t.Errorf("expected the sorted phase to time out without a solution, got %+v", stats)
+	}
+}
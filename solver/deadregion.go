@@ -0,0 +1,124 @@
+// Package solver contains the dead-region pruning of the backtracking search.
+package solver
+
+import "tetris-optimizer/tetris"
+
+// deadRegions counts the empty cells cut off in pockets smaller than the
+// smallest piece. No piece fits in such a pocket, and regions only shrink as
+// pieces are placed, so its cells stay empty for the rest of the search.
+//
+// The board has slack more free cells than the pieces cover, so once more
+// cells than that are wasted the remaining pieces cannot all be placed.
+// A placement can only close off pockets next to it, so each one is checked by
+// flood filling from its empty neighbours, never past the smallest piece size.
+type deadRegions struct {
+	threshold int // Cells of the smallest piece; smaller pockets are dead
+	slack     int // Free cells not needed by the pieces
+	wasted    int // Cells in dead pockets on the current board
+	seen      []int
+	stamp     int
+	stack     []tetris.Point
+}
+
+// newDeadRegions returns the tracker for placing pieces on board, with the
+// pockets already on the board, e.g. between blocked cells, counted.
+func newDeadRegions(board *tetris.Board, pieces []tetris.Piece) *deadRegions {
+	d := &deadRegions{
+		slack: board.Free() - board.Filled(),
+		seen:  make([]int, board.Width*board.Height),
+	}
+
+	for i, p := range pieces {
+		if i == 0 || len(p.Pos) < d.threshold {
+			d.threshold = len(p.Pos)
+		}
+
+		d.slack -= len(p.Pos)
+	}
+
+	base := d.stamp + 1
+
+	for y := range board.Height {
+		for x := range board.Width {
+			d.check(board, x, y, base)
+		}
+	}
+
+	return d
+}
+
+// feasible reports whether the wasted cells fit in the slack.
+func (d *deadRegions) feasible() bool {
+	return d.wasted <= d.slack
+}
+
+// place adds the pockets closed off by piece p, just placed at (x, y), to the
+// wasted cells and reports whether they still fit in the slack. Callers save
+// wasted beforehand to restore it when the piece is removed.
+func (d *deadRegions) place(board *tetris.Board, p tetris.Piece, x, y int) bool {
+	base := d.stamp + 1
+
+	for _, b := range p.Pos {
+		d.check(board, x+b.X-1, y+b.Y, base)
+		d.check(board, x+b.X+1, y+b.Y, base)
+		d.check(board, x+b.X, y+b.Y-1, base)
+		d.check(board, x+b.X, y+b.Y+1, base)
+	}
+
+	return d.feasible()
+}
+
+// check counts the region of empty cell (x, y) as wasted if it is a dead pocket.
+// Regions already reached by a flood fill since stamp base are skipped.
+func (d *deadRegions) check(board *tetris.Board, x, y, base int) {
+	if !board.Empty(x, y) || d.seen[y*board.Width+x] >= base {
+		return
+	}
+
+	if size := d.fill(board, x, y, base); size < d.threshold {
+		d.wasted += size
+	}
+}
+
+// fill returns the size of the region of empty cell (x, y), or the threshold
+// once it is known to be at least that large. Reaching a cell of an earlier
+// fill since stamp base also means a large region, as that fill stopped early
+// or it would have reached (x, y).
+func (d *deadRegions) fill(board *tetris.Board, x, y, base int) int {
+	d.stamp++
+	size := 0
+	stack := append(d.stack[:0], tetris.Point{X: x, Y: y})
+	d.seen[y*board.Width+x] = d.stamp
+
+	for len(stack) > 0 {
+		cell := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if size++; size >= d.threshold {
+			break
+		}
+
+		for _, n := range [4]tetris.Point{
+			{X: cell.X - 1, Y: cell.Y}, {X: cell.X + 1, Y: cell.Y},
+			{X: cell.X, Y: cell.Y - 1}, {X: cell.X, Y: cell.Y + 1},
+		} {
+			if !board.Empty(n.X, n.Y) {
+				continue
+			}
+
+			switch seen := d.seen[n.Y*board.Width+n.X]; {
+			case seen == d.stamp:
+				continue
+			case seen >= base:
+				d.stack = stack
+				return d.threshold
+			}
+
+			d.seen[n.Y*board.Width+n.X] = d.stamp
+			stack = append(stack, n)
+		}
+	}
+
+	d.stack = stack
+	return min(size, d.threshold)
+}
@@ -0,0 +1,143 @@
+package solver
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+
+	"tetris-optimizer/tetris"
+)
+
+// deadCells counts the empty cells of board in regions smaller than threshold
+// by flood filling every region from scratch.
+func deadCells(board *tetris.Board, threshold int) int {
+	seen := make([]bool, board.Width*board.Height)
+	dead := 0
+
+	var fill func(x, y int) int
+	fill = func(x, y int) int {
+		if !board.Empty(x, y) || seen[y*board.Width+x] {
+			return 0
+		}
+
+		seen[y*board.Width+x] = true
+
+		return 1 + fill(x-1, y) + fill(x+1, y) + fill(x, y-1) + fill(x, y+1)
+	}
+
+	for y := range board.Height {
+		for x := range board.Width {
+			if size := fill(x, y); size < threshold {
+				dead += size
+			}
+		}
+	}
+
+	return dead
+}
+
+func TestDeadRegionsInitial(t *testing.T) {
+	// Blocked cells cut the top-left corner off a 4×4 container:
+	//
+	//  .#..
+	//  ##..
+	//  ....
+	//  ..#.
+	board := tetris.NewBoard(4)
+	board.Block(1, 0)
+	board.Block(0, 1)
+	board.Block(1, 1)
+	board.Block(2, 3)
+
+	d := newDeadRegions(&board, []tetris.Piece{iPiece, tPiece})
+	if d.wasted != 1 || d.slack != 4 || !d.feasible() {
+		t.Errorf("expected 1 wasted cell and a slack of 4, got %d and %d", d.wasted, d.slack)
+	}
+}
+
+func TestDeadRegionsPlace(t *testing.T) {
+	bottomRow := []tetris.Point{{X: 0, Y: 3}, {X: 1, Y: 3}, {X: 2, Y: 3}, {X: 3, Y: 3}}
+
+	testData := []struct {
+		name     string
+		blocked  []tetris.Point
+		piece    tetris.Piece
+		x, y     int
+		wasted   int
+		feasible bool
+	}{
+		{"Open board", nil, iPiece, 0, 0, 0, true},
+		{"Corner cut off", nil, sPiece, 0, 0, 1, true},
+		{"Regions of a piece", bottomRow, iPiece, 0, 1, 0, true},
+		{"No slack", bottomRow, sPiece, 0, 0, 1, false},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			// The 3 pieces leave 4 cells of a 4×4 square empty, less any blocked cells.
+			board := tetris.NewBoard(4)
+			for _, b := range test.blocked {
+				board.Block(b.X, b.Y)
+			}
+
+			d := newDeadRegions(&board, []tetris.Piece{iPiece, tPiece, sPiece})
+
+			board.Place(test.piece, test.x, test.y)
+			if feasible := d.place(&board, test.piece, test.x, test.y); feasible != test.feasible {
+				t.Errorf("expected feasible %v, got %v", test.feasible, feasible)
+			}
+
+			if d.wasted != test.wasted {
+				t.Errorf("expected %d wasted cells, got %d:\n%s", test.wasted, d.wasted, board.ToString())
+			}
+		})
+	}
+}
+
+func TestDeadRegionsMatchesFullScan(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	shapes := []tetris.Piece{iPiece, tPiece, sPiece, lPiece}
+
+	for game := range 200 {
+		board := tetris.NewBoard(uint(4 + game%5))
+		d := newDeadRegions(&board, shapes)
+
+		for range 40 {
+			orientations := shapes[rng.IntN(len(shapes))]
+			orientations.Transforms = tetris.Rotate
+			p := orientations.Orientations()[rng.IntN(len(orientations.Orientations()))]
+			x, y := rng.IntN(board.Width), rng.IntN(board.Height)
+
+			if !board.CanPlace(p, x, y) {
+				continue
+			}
+
+			board.Place(p, x, y)
+			d.place(&board, p, x, y)
+
+			if expected := deadCells(&board, 4); d.wasted != expected {
+				t.Fatalf("expected %d wasted cells, got %d:\n%s", expected, d.wasted, board.ToString())
+			}
+		}
+	}
+}
+
+func TestBacktrackerPrunes(t *testing.T) {
+	// 9 I pieces cover a 6×6 square but cannot tile it.
+	pieces := make([]tetris.Piece, 9)
+	for i := range pieces {
+		pieces[i] = iPiece
+		pieces[i].ID = i
+	}
+
+	var counter Counter
+
+	board := tetris.NewBoard(6)
+	if (Backtracker{}).Solve(WithCounter(context.Background(), &counter), &board, pieces) {
+		t.Fatal("expected solve to fail")
+	}
+
+	if stats := counter.Stats(); stats.Pruned == 0 {
+		t.Errorf("expected pruned placements, got %+v", stats)
+	}
+}
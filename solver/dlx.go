@@ -0,0 +1,293 @@
+// Package solver contains a Dancing Links (Algorithm X) engine for the packing problem.
+package solver
+
+import (
+	"context"
+	"slices"
+
+	"tetris-optimizer/tetris"
+)
+
+// placement is a candidate position of a piece, one per row of the exact-cover matrix.
+type placement struct {
+	piece       int          // Index of the piece in the input
+	orientation tetris.Piece // Orientation the piece is placed in
+	x, y        int
+}
+
+// dlx is an exact-cover matrix stored as parallel index slices.
+//
+// Node 0 is the root header and nodes 1..columns are the column headers.
+// Piece columns are primary (must be covered exactly once) and cell columns
+// are secondary (covered at most once), so empty cells need no filler rows.
+// Secondary headers are left out of the root list and therefore never chosen.
+type dlx struct {
+	left, right, up, down []int
+	col                   []int // Column header of each node
+	row                   []int // Placement index of each node
+	size                  []int // Number of live nodes in each column
+	placements            []placement
+	solution              []int // Placement indices of the current partial cover
+	best                  []int // Largest partial cover seen, reported on cancellation
+	done                  <-chan struct{}
+	cancelled             bool
+	ops                   int   // Nodes visited, also throttling cancellation checks
+	chosen, backtracks    int64 // Rows added to and taken back from the partial cover
+}
+
+// newDLX builds the exact-cover matrix for placing pieces on the free cells of board.
+func newDLX(board *tetris.Board, pieces []tetris.Piece) *dlx {
+	primary := len(pieces)
+	columns := primary + board.Width*board.Height
+	d := &dlx{}
+
+	for i := 0; i <= columns; i++ {
+		d.left = append(d.left, i)
+		d.right = append(d.right, i)
+		d.up = append(d.up, i)
+		d.down = append(d.down, i)
+		d.col = append(d.col, i)
+		d.row = append(d.row, -1)
+		d.size = append(d.size, 0)
+	}
+
+	// Link the primary headers into the root list.
+	for i := 1; i <= primary; i++ {
+		d.left[i] = i - 1
+		d.right[i-1] = i
+	}
+
+	d.left[0] = primary
+	d.right[primary] = 0
+
+	cellColumn := func(x, y int) int {
+		return primary + 1 + y*board.Width + x
+	}
+
+	for i, piece := range pieces {
+		for _, o := range piece.Orientations() {
+			for y := 0; y <= board.Height-o.Height; y++ {
+				for x := 0; x <= board.Width-o.Width; x++ {
+					if !board.CanPlace(o, x, y) {
+						continue
+					}
+
+					columns := []int{i + 1}
+					for _, p := range o.Pos {
+						columns = append(columns, cellColumn(x+p.X, y+p.Y))
+					}
+
+					d.addRow(columns, placement{piece: i, orientation: o, x: x, y: y})
+				}
+			}
+		}
+	}
+
+	return d
+}
+
+// addRow appends a row with a node in each of the given columns.
+func (d *dlx) addRow(columns []int, p placement) {
+	rowIndex := len(d.placements)
+	first := len(d.col)
+
+	d.placements = append(d.placements, p)
+
+	for i, c := range columns {
+		n := len(d.col)
+
+		d.col = append(d.col, c)
+		d.row = append(d.row, rowIndex)
+		d.size[c]++
+
+		// Insert at the bottom of the column.
+		d.up = append(d.up, d.up[c])
+		d.down = append(d.down, c)
+		d.down[d.up[c]] = n
+		d.up[c] = n
+
+		// Insert at the end of the circular row list.
+		if i == 0 {
+			d.left = append(d.left, n)
+			d.right = append(d.right, n)
+			continue
+		}
+
+		d.left = append(d.left, d.left[first])
+		d.right = append(d.right, first)
+		d.right[d.left[first]] = n
+		d.left[first] = n
+	}
+}
+
+// cover removes a column header and every row intersecting it.
+func (d *dlx) cover(c int) {
+	d.right[d.left[c]] = d.right[c]
+	d.left[d.right[c]] = d.left[c]
+
+	for i := d.down[c]; i != c; i = d.down[i] {
+		for j := d.right[i]; j != i; j = d.right[j] {
+			d.down[d.up[j]] = d.down[j]
+			d.up[d.down[j]] = d.up[j]
+			d.size[d.col[j]]--
+		}
+	}
+}
+
+// uncover restores a column removed by cover, in exact reverse order.
+func (d *dlx) uncover(c int) {
+	for i := d.up[c]; i != c; i = d.up[i] {
+		for j := d.left[i]; j != i; j = d.left[j] {
+			d.size[d.col[j]]++
+			d.down[d.up[j]] = j
+			d.up[d.down[j]] = j
+		}
+	}
+
+	d.right[d.left[c]] = c
+	d.left[d.right[c]] = c
+}
+
+// chooseColumn returns the first primary column with the fewest rows.
+func (d *dlx) chooseColumn() int {
+	best := d.right[0]
+	for c := d.right[best]; c != 0; c = d.right[c] {
+		if d.size[c] < d.size[best] {
+			best = c
+		}
+	}
+
+	return best
+}
+
+// search runs Algorithm X, branching on the primary column with the fewest rows.
+func (d *dlx) search() bool {
+	d.ops++
+	if d.ops&1023 == 0 && !d.cancelled {
+		select {
+		case <-d.done:
+			d.cancelled = true
+		default:
+		}
+	}
+
+	if d.cancelled {
+		return false
+	}
+
+	if len(d.solution) > len(d.best) {
+		d.best = slices.Clone(d.solution)
+	}
+
+	if d.right[0] == 0 {
+		return true
+	}
+
+	best := d.chooseColumn()
+	if d.size[best] == 0 {
+		return false
+	}
+
+	d.cover(best)
+
+	for r := d.down[best]; r != best; r = d.down[r] {
+		d.solution = append(d.solution, d.row[r])
+		d.chosen++
+
+		for j := d.right[r]; j != r; j = d.right[j] {
+			d.cover(d.col[j])
+		}
+
+		if d.search() {
+			return true
+		}
+
+		if d.cancelled {
+			return false
+		}
+
+		for j := d.left[r]; j != r; j = d.left[j] {
+			d.uncover(d.col[j])
+		}
+
+		d.solution = d.solution[:len(d.solution)-1]
+		d.backtracks++
+	}
+
+	d.uncover(best)
+	return false
+}
+
+// apply places the pieces of the given cover on the board.
+func (d *dlx) apply(board *tetris.Board, cover []int) {
+	for _, index := range cover {
+		p := d.placements[index]
+		board.Place(p.orientation, p.x, p.y)
+	}
+}
+
+// stats returns the stats of the search, crediting DLX if it covered every piece.
+func (d *dlx) stats(solved bool) SearchStats {
+	stats := SearchStats{Nodes: int64(d.ops), Placements: d.chosen, Backtracks: d.backtracks, MaxDepth: len(d.best)}
+	if solved {
+		stats.Strategy = "dlx"
+	}
+
+	return stats
+}
+
+// solveDLX places all pieces on the board by solving the equivalent exact-cover problem.
+// If ctx is done first, the largest partial cover found is placed instead.
+func solveDLX(ctx context.Context, board *tetris.Board, pieces []tetris.Piece) bool {
+	d := newDLX(board, pieces)
+	d.done = ctx.Done()
+	solved := d.search()
+	record(ctx, d.stats(solved))
+
+	if solved {
+		d.apply(board, d.solution)
+		return true
+	}
+
+	if d.cancelled {
+		d.apply(board, d.best)
+	}
+
+	return false
+}
+
+// DLX solves each board as an exact-cover problem with Knuth's Algorithm X.
+type DLX struct{}
+
+// Solve implements Solver.
+func (DLX) Solve(ctx context.Context, board *tetris.Board, pieces []tetris.Piece) bool {
+	if !parityFeasible(board, pieces) {
+		return false
+	}
+
+	return solveDLX(ctx, board, pieces)
+}
+
+// Split implements Splitter by taking each row of the column chosen at the root.
+// Rebuilding the matrix for a branch yields the same rows and column order as
+// covering that row in place, so each branch is searched as in the sequential run.
+func (DLX) Split(board *tetris.Board, pieces []tetris.Piece) []Branch {
+	d := newDLX(board, pieces)
+	column := d.chooseColumn()
+
+	var branches []Branch
+
+	for r := d.down[column]; r != column; r = d.down[r] {
+		p := d.placements[d.row[r]]
+		branch := Branch{
+			Board:  board.Clone(),
+			Pieces: slices.Delete(slices.Clone(pieces), p.piece, p.piece+1),
+			Solver: DLX{},
+		}
+
+		branch.Board.Place(p.orientation, p.x, p.y)
+		branches = append(branches, branch)
+	}
+
+	return branches
+}
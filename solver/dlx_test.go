@@ -0,0 +1,80 @@
+package solver
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"tetris-optimizer/tetris"
+)
+
+func TestSolveDLX(t *testing.T) {
+	iPiece := tetris.Piece{
+		Pos:    []tetris.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 3, Y: 0}},
+		Width:  4,
+		Height: 1,
+		ID:     0,
+	}
+	testData := []struct {
+		name     string
+		size     uint
+		pieces   []tetris.Piece
+		expected bool
+	}{
+		{"Empty list", 2, []tetris.Piece{}, true},
+		{"Single piece", 4, []tetris.Piece{iPiece}, true},
+		{"Too small board", 3, []tetris.Piece{iPiece}, false},
+		{"Exact fit", 4, []tetris.Piece{iPiece, iPiece, iPiece, iPiece}, true},
+		{"Over capacity", 4, []tetris.Piece{iPiece, iPiece, iPiece, iPiece, iPiece}, false},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			board := tetris.NewBoard(test.size)
+			output := solveDLX(context.Background(), &board, test.pieces)
+			if output != test.expected {
+				t.Fatalf("expected solveDLX() == %v, got %v", test.expected, output)
+			}
+
+			if !output {
+				return
+			}
+
+			filled := board.Width*board.Height - strings.Count(board.ToString(), ".")
+			if filled != 4*len(test.pieces) {
+				t.Errorf("expected %d filled cells, got %d:\n%s", 4*len(test.pieces), filled, board.ToString())
+			}
+		})
+	}
+}
+
+func TestSolveDLXCancelled(t *testing.T) {
+	iPiece := tetris.Piece{
+		Pos:    []tetris.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 3, Y: 0}},
+		Width:  4,
+		Height: 1,
+		ID:     0,
+	}
+	// 17 I pieces cannot fit in an 8×8 board, and proving so takes far longer than the deadline.
+	pieces := make([]tetris.Piece, 17)
+	for i := range pieces {
+		pieces[i] = iPiece
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	board := tetris.NewBoard(8)
+	if solveDLX(ctx, &board, pieces) {
+		t.Fatal("expected solve to fail")
+	}
+
+	if ctx.Err() == nil {
+		t.Fatal("expected search to run until the deadline")
+	}
+
+	if strings.Count(board.ToString(), ".") == 64 {
+		t.Error("expected partial placement on cancelled board")
+	}
+}
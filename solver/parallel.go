@@ -0,0 +1,182 @@
+// Package solver contains the parallel root-splitting wrapper.
+package solver
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"tetris-optimizer/tetris"
+)
+
+// Branch is an independent subproblem at the root of a search tree.
+type Branch struct {
+	Board  tetris.Board
+	Pieces []tetris.Piece
+	Solver Solver // Solves the branch exactly as the parent search would
+}
+
+// Splitter is implemented by solvers whose search tree can be split at the root.
+type Splitter interface {
+	// Split returns the root branches in the order the sequential search visits them.
+	// It is only called with at least one piece.
+	Split(board *tetris.Board, pieces []tetris.Piece) []Branch
+}
+
+// Abandoner is implemented by branch solvers that can give up on the strategy
+// of their split, as the branches of Hybrid do when its sorted phase times out.
+// Parallel then splits the board again, as the sequential search restarts.
+type Abandoner interface {
+	// Abandoned reports whether the last Solve gave up without settling the branch.
+	Abandoned() bool
+}
+
+// Parallel solves the root branches of a Splitter on a pool of workers.
+// Solvers that cannot be split are run as they are.
+type Parallel struct {
+	Inner         Solver
+	Workers       int  // Zero means runtime.GOMAXPROCS(0)
+	Deterministic bool // Return the branch the sequential search would find first
+}
+
+// Solve implements Solver.
+//
+// As soon as a branch succeeds, the branches that can no longer win are
+// cancelled. In deterministic mode, earlier branches still run to completion
+// so that the first successful branch in sequential order is returned.
+// If a branch that could decide the result was abandoned, the board is split
+// again and the new branches are run.
+func (p Parallel) Solve(ctx context.Context, board *tetris.Board, pieces []tetris.Piece) bool {
+	splitter, ok := p.Inner.(Splitter)
+	if !ok || len(pieces) == 0 {
+		return p.Inner.Solve(ctx, board, pieces)
+	}
+
+	if !parityFeasible(board, pieces) {
+		return false
+	}
+
+	for {
+		branches := splitter.Split(board, pieces)
+		winner := p.run(ctx, branches)
+
+		if ctx.Err() == nil && p.abandoned(branches, winner) {
+			continue
+		}
+
+		// In deterministic mode a cancelled earlier branch might have held the answer.
+		if winner < len(branches) && (!p.Deterministic || ctx.Err() == nil) {
+			*board = branches[winner].Board
+			return true
+		}
+
+		if ctx.Err() != nil {
+			best := board.Clone()
+
+			for _, branch := range branches {
+				if branch.Board.Filled() > best.Filled() {
+					best = branch.Board
+				}
+			}
+
+			*board = best
+		}
+
+		return false
+	}
+}
+
+// abandoned reports whether a branch that could have decided the result gave up.
+// Without a winner every branch could have; in deterministic mode, those before it.
+func (p Parallel) abandoned(branches []Branch, winner int) bool {
+	if !p.Deterministic && winner < len(branches) {
+		return false
+	}
+
+	for _, branch := range branches[:winner] {
+		if a, ok := branch.Solver.(Abandoner); ok && a.Abandoned() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// run solves the branches on the pool of workers and returns the index of the
+// winning branch, or len(branches) if none succeeded.
+func (p Parallel) run(ctx context.Context, branches []Branch) int {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		winner  = len(branches) // Lowest successful branch so far
+		cancels = make([]context.CancelFunc, len(branches))
+		jobs    = make(chan int)
+	)
+
+	// canWin reports whether branch i may still be returned. Callers hold mu.
+	canWin := func(i int) bool {
+		if p.Deterministic {
+			return i < winner
+		}
+
+		return winner == len(branches)
+	}
+
+	for range min(workers, len(branches)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				mu.Lock()
+				if !canWin(i) || ctx.Err() != nil {
+					mu.Unlock()
+					continue
+				}
+
+				branchCtx, cancel := context.WithCancel(ctx)
+				cancels[i] = cancel
+				mu.Unlock()
+
+				var counter Counter
+
+				branch := &branches[i]
+				solved := branch.Solver.Solve(WithCounter(branchCtx, &counter), &branch.Board, branch.Pieces)
+				cancel()
+
+				// The branch search starts below the piece placed by the split.
+				record(ctx, SearchStats{Placements: 1, MaxDepth: counter.Stats().MaxDepth + 1})
+
+				if !solved {
+					continue
+				}
+
+				mu.Lock()
+				if i < winner {
+					winner = i
+
+					for j, cancelOther := range cancels {
+						if cancelOther != nil && j != i && !canWin(j) {
+							cancelOther()
+						}
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range branches {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return winner
+}
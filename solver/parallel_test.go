@@ -0,0 +1,196 @@
+package solver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tetris-optimizer/tetris"
+)
+
+var (
+	iPiece = tetris.Piece{
+		Pos:    []tetris.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 3, Y: 0}},
+		Width:  4,
+		Height: 1,
+		ID:     0,
+	}
+	tPiece = tetris.Piece{
+		Pos:    []tetris.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 1, Y: 1}},
+		Width:  3,
+		Height: 2,
+		ID:     1,
+	}
+	sPiece = tetris.Piece{
+		Pos:    []tetris.Point{{X: 1, Y: 0}, {X: 2, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}},
+		Width:  3,
+		Height: 2,
+		ID:     2,
+	}
+	lPiece = tetris.Piece{
+		Pos:    []tetris.Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}, {X: 1, Y: 2}},
+		Width:  2,
+		Height: 3,
+		ID:     3,
+	}
+)
+
+func TestParallelMatchesSequential(t *testing.T) {
+	pieces := []tetris.Piece{tPiece, sPiece, lPiece, iPiece, tPiece, lPiece}
+	for i := range pieces {
+		pieces[i].ID = i
+	}
+
+	testData := []struct {
+		name  string
+		inner Solver
+	}{
+		{"backtrack", Backtracker{}},
+		{"sorted", Backtracker{Sorted: true}},
+		{"hybrid", &Hybrid{Timeout: DefaultHybridTimeout}},
+		{"dlx", DLX{}},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			sequential := tetris.NewBoard(6)
+			if !test.inner.Solve(context.Background(), &sequential, pieces) {
+				t.Fatal("expected sequential solve to succeed")
+			}
+
+			for _, workers := range []int{1, 2, 8} {
+				parallel := tetris.NewBoard(6)
+				p := Parallel{Inner: test.inner, Workers: workers, Deterministic: true}
+
+				if !p.Solve(context.Background(), &parallel, pieces) {
+					t.Fatalf("expected parallel solve with %d workers to succeed", workers)
+				}
+
+				if !parallel.Equal(sequential) {
+					t.Fatalf("with %d workers expected:\n%s\ngot:\n%s",
+						workers, sequential.ToString(), parallel.ToString())
+				}
+			}
+		})
+	}
+}
+
+func TestParallelNonDeterministic(t *testing.T) {
+	pieces := []tetris.Piece{iPiece, iPiece, iPiece, iPiece}
+	board := tetris.NewBoard(4)
+
+	if !(Parallel{Inner: DLX{}}).Solve(context.Background(), &board, pieces) {
+		t.Fatal("expected solve to succeed")
+	}
+
+	if board.Filled() != 16 {
+		t.Fatalf("expected a full board, got:\n%s", board.ToString())
+	}
+
+	board = tetris.NewBoard(3)
+	if (Parallel{Inner: Backtracker{}}).Solve(context.Background(), &board, pieces[:2]) {
+		t.Fatal("expected solve to fail on a board narrower than the piece")
+	}
+}
+
+func TestParallelUnsplittable(t *testing.T) {
+	board := tetris.NewBoard(4)
+	p := Parallel{Inner: CellFirst{}}
+
+	if !p.Solve(context.Background(), &board, []tetris.Piece{iPiece}) {
+		t.Fatal("expected solve to succeed")
+	}
+}
+
+func TestParallelCancelled(t *testing.T) {
+	pieces := unfillableSquare()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	board := tetris.NewBoard(10)
+	if (Parallel{Inner: Backtracker{}, Workers: 4}).Solve(ctx, &board, pieces) {
+		t.Fatal("expected solve to fail")
+	}
+
+	if board.Filled() == 0 {
+		t.Error("expected partial placement on cancelled board")
+	}
+}
+
+func TestHybridSplit(t *testing.T) {
+	pieces := []tetris.Piece{tPiece, sPiece, lPiece, iPiece, tPiece, lPiece}
+	for i := range pieces {
+		pieces[i].ID = i
+	}
+
+	board := tetris.NewBoard(6)
+
+	s, _ := New(DefaultName)
+	h, ok := s.(*Hybrid)
+	if !ok {
+		t.Fatalf("expected the default solver to be the hybrid one, got %T", s)
+	}
+
+	branches := h.Split(&board, pieces)
+	if sorted := (Backtracker{Sorted: true}).Split(&board, pieces); len(branches) < 2 || len(branches) != len(sorted) {
+		t.Fatalf("expected the %d branches of the sorted search, got %d", len(sorted), len(branches))
+	}
+
+	for _, branch := range branches {
+		if len(branch.Pieces) != len(pieces)-1 {
+			t.Fatalf("expected %d pieces below the root, got %d", len(pieces)-1, len(branch.Pieces))
+		}
+	}
+
+	h.trapped.Store(true)
+
+	if input := (Backtracker{}).Split(&board, pieces); len(h.Split(&board, pieces)) != len(input) {
+		t.Errorf("expected the %d branches of input order once trapped", len(input))
+	}
+}
+
+func TestHybridSplitTrapped(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var counter Counter
+
+	board := tetris.NewBoard(10)
+	h := &Hybrid{Timeout: 20 * time.Millisecond}
+
+	if (Parallel{Inner: h, Workers: 2}).Solve(WithCounter(ctx, &counter), &board, unfillableSquare()) {
+		t.Fatal("expected solve to fail")
+	}
+
+	if !h.trapped.Load() {
+		t.Error("expected the sorted phase of the branches to time out")
+	}
+
+	if stats := counter.Stats(); !stats.HeuristicTimedOut {
+		t.Errorf("expected the timeout in the stats, got %+v", stats)
+	}
+}
+
+func TestHybridSplitSharedIDs(t *testing.T) {
+	// Pieces built without IDs all have ID 0.
+	pieces := []tetris.Piece{tPiece, sPiece, lPiece, iPiece, tPiece, lPiece, sPiece, iPiece}
+	for i := range pieces {
+		pieces[i].ID = 0
+	}
+
+	board := tetris.NewBoard(6)
+	h := &Hybrid{Timeout: time.Nanosecond}
+
+	if !(Parallel{Inner: h, Workers: 2}).Solve(context.Background(), &board, pieces) {
+		t.Fatal("expected solve to succeed")
+	}
+
+	if !h.trapped.Load() {
+		t.Error("expected the sorted phase of the branches to time out")
+	}
+
+	if cells := 4 * len(pieces); board.Filled() != cells {
+		t.Errorf("expected all %d cells of the pieces on the board, got %d", cells, board.Filled())
+	}
+}
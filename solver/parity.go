@@ -0,0 +1,37 @@
+// Package solver contains the checkerboard parity check shared by the solvers.
+package solver
+
+import "tetris-optimizer/tetris"
+
+// parityFeasible reports whether the checkerboard colouring of the free cells
+// allows a set of tetrominoes to be placed on the board.
+//
+// Every tetromino but the T covers two cells of each colour in any position,
+// while a T covers three of one colour and one of the other. With n pieces of
+// which k are T pieces, a placement where a of the T pieces cover three even
+// cells covers 2n-k+2a even cells and 2n+k-2a odd ones, and both must fit.
+// For example, 9 pieces with a single T never fill a 6×6 square.
+//
+// Sets with pieces other than tetrominoes are not checked and always pass.
+func parityFeasible(board *tetris.Board, pieces []tetris.Piece) bool {
+	counts := tetris.CountShapes(pieces)
+	if counts[tetris.NoShape] > 0 {
+		return true
+	}
+
+	n, k := len(pieces), counts[tetris.ShapeT]
+	even, odd := board.FreeByParity()
+
+	// Bounds on a from each colour; hiTwice is 2a at most, loTwice 2a at least.
+	hiTwice := even - 2*n + k
+	loTwice := 2*n + k - odd
+
+	if hiTwice < 0 {
+		return false
+	}
+
+	lo := max(0, (loTwice+1)/2)
+	hi := min(k, hiTwice/2)
+
+	return lo <= hi
+}
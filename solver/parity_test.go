@@ -0,0 +1,53 @@
+package solver
+
+import (
+	"testing"
+
+	"tetris-optimizer/tetris"
+)
+
+func TestParityFeasible(t *testing.T) {
+	oPiece := tetris.Piece{
+		Pos:    []tetris.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}},
+		Width:  2,
+		Height: 2,
+	}
+	monomino := tetris.Piece{Pos: []tetris.Point{{X: 0, Y: 0}}, Width: 1, Height: 1}
+
+	repeat := func(p tetris.Piece, n int) []tetris.Piece {
+		pieces := make([]tetris.Piece, n)
+		for i := range pieces {
+			pieces[i] = p
+		}
+
+		return pieces
+	}
+
+	blockedCorner := tetris.NewBoard(5)
+	blockedCorner.Block(0, 0)
+
+	testData := []struct {
+		name     string
+		board    tetris.Board
+		pieces   []tetris.Piece
+		expected bool
+	}{
+		{"Even number of T pieces fill a square", tetris.NewBoard(4), repeat(tPiece, 4), true},
+		{"Odd number of T pieces cannot fill a square", tetris.NewBoard(6), append(repeat(tPiece, 1), repeat(oPiece, 8)...), false},
+		{"Odd number of T pieces with room to spare", tetris.NewBoard(6), append(repeat(tPiece, 1), repeat(oPiece, 7)...), true},
+		{"Three T pieces cannot fill a square", tetris.NewBoard(4), append(repeat(tPiece, 3), oPiece), false},
+		{"Too many cells", tetris.NewBoard(4), repeat(iPiece, 5), false},
+		{"One T piece leaves an odd cell", tetris.NewBoard(5), append(repeat(tPiece, 1), repeat(oPiece, 5)...), true},
+		{"Blocked cell of the majority colour", blockedCorner, append(repeat(tPiece, 1), repeat(oPiece, 5)...), false},
+		{"Blocked cells with balanced pieces", blockedCorner, append(repeat(lPiece, 2), repeat(oPiece, 4)...), true},
+		{"Other polyominoes are not checked", tetris.NewBoard(4), append(repeat(iPiece, 4), monomino), true},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parityFeasible(&test.board, test.pieces); got != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
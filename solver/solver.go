@@ -0,0 +1,81 @@
+// Package solver defines the Solver interface and a registry of named search strategies.
+package solver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"tetris-optimizer/tetris"
+)
+
+// Solver places pieces on a board of fixed size.
+type Solver interface {
+	// Solve tries to place every piece on the free cells of board.
+	// On success the board holds the placement and true is returned.
+	// If the pieces cannot fit, false is returned and the board is left as it
+	// was given. If ctx is done before the search completes, false is returned
+	// and the board holds the largest partial placement found.
+	Solve(ctx context.Context, board *tetris.Board, pieces []tetris.Piece) bool
+}
+
+// Factory creates a fresh Solver for a single search.
+// Solvers may keep state across board sizes so each search needs its own instance.
+type Factory func() Solver
+
+// DefaultName is the strategy used when none is requested.
+const DefaultName = "hybrid"
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a strategy available under name.
+// It panics if the name is already taken, as that is a programming error.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("solver: %q registered twice", name))
+	}
+
+	registry[name] = factory
+}
+
+// New returns a fresh instance of the strategy registered under name.
+func New(name string) (Solver, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown solver %q", name)
+	}
+
+	return factory(), nil
+}
+
+// Names returns the registered strategy names in sorted order.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+	return names
+}
+
+func init() {
+	Register("backtrack", func() Solver { return Backtracker{} })
+	Register("sorted", func() Solver { return Backtracker{Sorted: true} })
+	Register("hybrid", func() Solver { return &Hybrid{Timeout: DefaultHybridTimeout} })
+	Register("dlx", func() Solver { return DLX{} })
+	Register("cell", func() Solver { return CellFirst{} })
+}
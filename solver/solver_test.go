@@ -0,0 +1,94 @@
+package solver
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"tetris-optimizer/tetris"
+)
+
+func TestNames(t *testing.T) {
+	names := Names()
+
+	for _, name := range []string{"backtrack", "sorted", "hybrid", "dlx", "cell", DefaultName} {
+		if !slices.Contains(names, name) {
+			t.Errorf("expected %q to be registered, got %v", name, names)
+		}
+	}
+
+	if !slices.IsSorted(names) {
+		t.Errorf("expected sorted names, got %v", names)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("Fresh instances", func(t *testing.T) {
+		a, err := New("hybrid")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		b, _ := New("hybrid")
+		if a == b {
+			t.Fatal("expected each call to return a new solver")
+		}
+	})
+
+	t.Run("Unknown name", func(t *testing.T) {
+		expectedMsg := `unknown solver "sat"`
+		_, err := New("sat")
+		if err == nil {
+			t.Fatal("expected error but got nil")
+		}
+
+		if err.Error() != expectedMsg {
+			t.Errorf("expected error %q, got %q", expectedMsg, err.Error())
+		}
+	})
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic on duplicate registration")
+		}
+	}()
+
+	Register("backtrack", func() Solver { return Backtracker{} })
+}
+
+func TestSolversContainer(t *testing.T) {
+	testData := []struct {
+		name     string
+		blocked  []tetris.Point
+		expected string
+	}{
+		{"Staggered", []tetris.Point{{X: 0, Y: 0}, {X: 4, Y: 1}}, "#AAAA\nBBBB#\n"},
+		{"Split rows", []tetris.Point{{X: 2, Y: 0}, {X: 2, Y: 1}}, ""},
+	}
+
+	for _, name := range Names() {
+		for _, test := range testData {
+			t.Run(name+"/"+test.name, func(t *testing.T) {
+				board := tetris.NewRectBoard(5, 2)
+				for _, p := range test.blocked {
+					board.Block(p.X, p.Y)
+				}
+
+				s, _ := New(name)
+				pieces := []tetris.Piece{iPiece, iPiece}
+				pieces[1].ID = 1
+
+				solved := s.Solve(context.Background(), &board, pieces)
+				if solved != (test.expected != "") {
+					t.Fatalf("expected Solve() == %v, got %v", test.expected != "", solved)
+				}
+
+				if solved && board.ToString() != test.expected {
+					t.Fatalf("expected:\n%s\ngot:\n%s", test.expected, board.ToString())
+				}
+			})
+		}
+	}
+}
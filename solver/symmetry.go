@@ -0,0 +1,157 @@
+// Package solver contains the symmetry breaking shared by the backtracking solvers.
+package solver
+
+import "tetris-optimizer/tetris"
+
+// symmetry makes a search place each group of identical pieces in one order
+// only, instead of in every permutation of it.
+//
+// Placements are ranked by orientation, then row, then column, the order solve
+// visits them in. A piece identical to one placed earlier in the search must
+// take a higher rank. Any solution has exactly one assignment of identical
+// pieces that satisfies this, since two of them never share a rank.
+type symmetry struct {
+	after []int // Search position of the previous identical piece, or -1
+	ranks []int // Rank of the placement at each search position
+}
+
+// newSymmetry returns the symmetry of pieces, given in search order.
+func newSymmetry(pieces []tetris.Piece) symmetry {
+	s := symmetry{after: make([]int, len(pieces)), ranks: make([]int, len(pieces))}
+
+	for _, group := range tetris.IdenticalGroups(pieces) {
+		previous := -1
+
+		for _, i := range group {
+			s.after[i] = previous
+			previous = i
+		}
+	}
+
+	return s
+}
+
+// floor returns the rank the placement at search position depth must exceed,
+// or -1 if it may take any rank.
+func (s symmetry) floor(depth int) int {
+	if s.after[depth] < 0 {
+		return -1
+	}
+
+	return s.ranks[s.after[depth]]
+}
+
+// rank returns the rank of placing orientation o at (x, y) on board.
+func rank(board *tetris.Board, o, x, y int) int {
+	return (o*board.Height+y)*board.Width + x
+}
+
+// squareSymmetry restricts the first piece of a search on an empty square to
+// one fundamental region of the symmetries of the square.
+//
+// A rotation or reflection of a solution is another solution whenever it maps
+// every piece onto one of its allowed orientations. So some image of any
+// solution places the first piece at the lowest rank its placement takes under
+// those symmetries, and placements that a symmetry maps to a lower rank can be
+// skipped. As the search visits placements in rank order, the first solution it
+// finds is never one of them, so only infeasible sizes are searched faster.
+type squareSymmetry struct {
+	maps   []func(p tetris.Point) tetris.Point // Symmetries kept by the pieces, without the identity
+	images [][]int                             // Orientation of the first piece under each map, by orientation
+}
+
+// newSquareSymmetry returns the symmetry of board for pieces, given in search
+// order, or nil if board is not an empty square or no symmetry keeps every piece.
+func newSquareSymmetry(board *tetris.Board, pieces []tetris.Piece) *squareSymmetry {
+	if len(pieces) == 0 || board.Width != board.Height ||
+		board.Free() != board.Width*board.Height || board.Filled() != 0 {
+		return nil
+	}
+
+	n := board.Width - 1
+	candidates := []func(p tetris.Point) tetris.Point{
+		func(p tetris.Point) tetris.Point { return tetris.Point{X: n - p.Y, Y: p.X} },     // Quarter turn
+		func(p tetris.Point) tetris.Point { return tetris.Point{X: n - p.X, Y: n - p.Y} }, // Half turn
+		func(p tetris.Point) tetris.Point { return tetris.Point{X: p.Y, Y: n - p.X} },     // Three quarters
+		func(p tetris.Point) tetris.Point { return tetris.Point{X: n - p.X, Y: p.Y} },     // Vertical axis
+		func(p tetris.Point) tetris.Point { return tetris.Point{X: p.X, Y: n - p.Y} },     // Horizontal axis
+		func(p tetris.Point) tetris.Point { return tetris.Point{X: p.Y, Y: p.X} },         // Main diagonal
+		func(p tetris.Point) tetris.Point { return tetris.Point{X: n - p.Y, Y: n - p.X} }, // Anti-diagonal
+	}
+
+	// Identical pieces share their orientations, so one piece of each group is checked.
+	var groups [][]tetris.Piece
+
+	for _, group := range tetris.IdenticalGroups(pieces) {
+		groups = append(groups, pieces[group[0]].Orientations())
+	}
+
+	s := &squareSymmetry{}
+
+	for _, f := range candidates {
+		// The first piece leads the first group, so its orientations come first.
+		images, ok := imageOrientations(f, groups[0])
+
+		for _, orientations := range groups[1:] {
+			if !ok {
+				break
+			}
+
+			_, ok = imageOrientations(f, orientations)
+		}
+
+		if ok {
+			s.maps = append(s.maps, f)
+			s.images = append(s.images, images)
+		}
+	}
+
+	if len(s.maps) == 0 {
+		return nil
+	}
+
+	return s
+}
+
+// imageOrientations returns the index of the image under f of each of the
+// orientations, if they are all among them.
+func imageOrientations(f func(p tetris.Point) tetris.Point, orientations []tetris.Piece) ([]int, bool) {
+	index := make(map[string]int, len(orientations))
+	for o, orientation := range orientations {
+		index[tetris.ShapeKey(orientation.Pos)] = o
+	}
+
+	images := make([]int, len(orientations))
+
+	for o, orientation := range orientations {
+		image := make([]tetris.Point, len(orientation.Pos))
+		for i, b := range orientation.Pos {
+			image[i] = f(b)
+		}
+
+		var ok bool
+		if images[o], ok = index[tetris.ShapeKey(image)]; !ok {
+			return nil, false
+		}
+	}
+
+	return images, true
+}
+
+// canonical reports whether placing orientation o of the first piece, current,
+// at (x, y) on board ranks no higher than any of its images.
+func (s *squareSymmetry) canonical(board *tetris.Board, o, x, y int, current tetris.Piece) bool {
+	r := rank(board, o, x, y)
+
+	for k, f := range s.maps {
+		// The bounding box of the piece maps onto the bounding box of its image.
+		a := f(tetris.Point{X: x, Y: y})
+		b := f(tetris.Point{X: x + current.Width - 1, Y: y + current.Height - 1})
+
+		if rank(board, s.images[k][o], min(a.X, b.X), min(a.Y, b.Y)) < r {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,180 @@
+package solver
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"tetris-optimizer/tetris"
+)
+
+func TestNewSymmetry(t *testing.T) {
+	pieces := []tetris.Piece{tPiece, iPiece, tPiece, sPiece, tPiece, iPiece}
+	expected := []int{-1, -1, 0, -1, 2, 1}
+
+	if s := newSymmetry(pieces); !slices.Equal(s.after, expected) {
+		t.Errorf("expected %v, got %v", expected, s.after)
+	}
+}
+
+func TestSymmetryCutsPermutations(t *testing.T) {
+	testData := []struct {
+		name   string
+		pieces []tetris.Piece
+		size   uint
+	}{
+		// 9 I pieces cover a 6×6 square but cannot tile it.
+		{"Identical", slices.Repeat([]tetris.Piece{iPiece}, 9), 6},
+		{"Two groups", []tetris.Piece{sPiece, iPiece, sPiece, iPiece, sPiece, iPiece}, 5},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			pieces := slices.Clone(test.pieces)
+			for i := range pieces {
+				pieces[i].ID = i
+			}
+
+			// Both searches are exhaustive, once with every piece distinct.
+			ops := make([]int, 2)
+
+			for i, breaking := range []bool{true, false} {
+				board := tetris.NewBoard(test.size)
+				state := newSearchState(context.Background(), &board, pieces)
+
+				if !breaking {
+					for j := range state.sym.after {
+						state.sym.after[j] = -1
+					}
+				}
+
+				if solve(&board, orientations(pieces), state) {
+					t.Fatal("expected solve to fail")
+				}
+
+				ops[i] = state.ops
+			}
+
+			if ops[0] >= ops[1] {
+				t.Errorf("expected fewer nodes with symmetry breaking, got %d against %d", ops[0], ops[1])
+			}
+		})
+	}
+}
+
+func TestIdenticalPiecesSolved(t *testing.T) {
+	pieces := []tetris.Piece{sPiece, sPiece, tPiece, sPiece, tPiece, iPiece, tPiece}
+	for i := range pieces {
+		pieces[i].ID = i
+	}
+
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			s, _ := New(name)
+
+			board := tetris.NewBoard(6)
+			if !s.Solve(context.Background(), &board, pieces) {
+				t.Fatalf("expected solve to succeed")
+			}
+
+			if cells := board.Cells(); len(cells) != len(pieces) {
+				t.Errorf("expected all %d pieces on the board, got:\n%s", len(pieces), board.ToString())
+			}
+		})
+	}
+}
+
+func TestNewSquareSymmetry(t *testing.T) {
+	rotating := func(p tetris.Piece, transforms tetris.Transform) tetris.Piece {
+		p.Transforms = transforms
+		return p
+	}
+
+	blocked := tetris.NewBoard(6)
+	blocked.Block(0, 0)
+
+	testData := []struct {
+		name   string
+		board  tetris.Board
+		pieces []tetris.Piece
+		maps   int // Symmetries kept, without the identity
+	}{
+		{"Fixed T", tetris.NewBoard(6), []tetris.Piece{tPiece}, 1},
+		{"Fixed I", tetris.NewBoard(6), []tetris.Piece{iPiece, iPiece}, 3},
+		{"Fixed I and T", tetris.NewBoard(6), []tetris.Piece{iPiece, tPiece}, 1},
+		{"Fixed I and S", tetris.NewBoard(6), []tetris.Piece{iPiece, sPiece}, 1},
+		{"Fixed L", tetris.NewBoard(6), []tetris.Piece{lPiece}, 0},
+		{"Rotating S", tetris.NewBoard(6), []tetris.Piece{rotating(sPiece, tetris.Rotate)}, 3},
+		{"Rotating and mirrored S", tetris.NewBoard(6), []tetris.Piece{rotating(sPiece, tetris.Rotate|tetris.Mirror)}, 7},
+		{"Rotating T", tetris.NewBoard(6), []tetris.Piece{rotating(tPiece, tetris.Rotate)}, 7},
+		{"Rectangle", tetris.NewRectBoard(6, 5), []tetris.Piece{rotating(tPiece, tetris.Rotate)}, 0},
+		{"Blocked cell", blocked, []tetris.Piece{rotating(tPiece, tetris.Rotate)}, 0},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			s := newSquareSymmetry(&test.board, test.pieces)
+
+			maps := 0
+			if s != nil {
+				maps = len(s.maps)
+			}
+
+			if maps != test.maps {
+				t.Errorf("expected %d symmetries, got %d", test.maps, maps)
+			}
+		})
+	}
+}
+
+func TestSquareSymmetryKeepsFirstSolution(t *testing.T) {
+	rotating := []tetris.Piece{tPiece, sPiece, lPiece, iPiece, tPiece, lPiece}
+	for i := range rotating {
+		rotating[i].Transforms = tetris.Rotate
+	}
+
+	testData := []struct {
+		name   string
+		pieces []tetris.Piece
+		size   uint
+	}{
+		{"Fixed", []tetris.Piece{tPiece, sPiece, lPiece, iPiece, tPiece, lPiece}, 6},
+		{"Rotating", rotating, 5},
+		{"Infeasible", slices.Repeat([]tetris.Piece{iPiece}, 9), 6},
+		{"Infeasible rotating", slices.Repeat(rotating[3:4], 9), 6},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			pieces := slices.Clone(test.pieces)
+			for i := range pieces {
+				pieces[i].ID = i
+			}
+
+			boards := make([]tetris.Board, 2)
+			ops := make([]int, 2)
+			solved := make([]bool, 2)
+
+			for i, breaking := range []bool{true, false} {
+				boards[i] = tetris.NewBoard(test.size)
+				state := newSearchState(context.Background(), &boards[i], pieces)
+
+				if !breaking {
+					state.square = nil
+				}
+
+				solved[i] = solve(&boards[i], orientations(pieces), state)
+				ops[i] = state.ops
+			}
+
+			if solved[0] != solved[1] || !boards[0].Equal(boards[1]) {
+				t.Fatalf("expected the same result with symmetry breaking, got:\n%s\nand without:\n%s",
+					boards[0].ToString(), boards[1].ToString())
+			}
+
+			if ops[0] > ops[1] {
+				t.Errorf("expected no more nodes with symmetry breaking, got %d against %d", ops[0], ops[1])
+			}
+		})
+	}
+}
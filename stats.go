@@ -0,0 +1,76 @@
+// Package main contains the search statistics reported by --stats.
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"tetris-optimizer/solver"
+)
+
+// SizeStats describes the search of one board size.
+type SizeStats struct {
+	Size int
+	solver.SearchStats
+	Elapsed     time.Duration
+	Solved      bool
+	Interrupted bool // The search was cancelled before settling the size
+}
+
+// Stats describes a search for the smallest board, one entry per size settled
+// in increasing order.
+type Stats struct {
+	Sizes   []SizeStats
+	Elapsed time.Duration
+}
+
+// Total returns the stats of all sizes together, with the strategy that placed
+// every piece if one did.
+func (s Stats) Total() solver.SearchStats {
+	var total solver.SearchStats
+
+	for _, size := range s.Sizes {
+		total.Add(size.SearchStats)
+	}
+
+	return total
+}
+
+// result describes how the search of a size ended.
+func (s SizeStats) result() string {
+	switch {
+	case s.Solved:
+		return "solved by " + s.Strategy
+	case s.Interrupted:
+		return "interrupted"
+	default:
+		return "infeasible"
+	}
+}
+
+// formatStats renders the stats as a table with a line per size and a total.
+func formatStats(stats Stats, solverName string) string {
+	var sb strings.Builder
+
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SIZE\tNODES\tPLACEMENTS\tBACKTRACKS\tPRUNED\tMAX DEPTH\tTIME\tRESULT")
+
+	for _, s := range stats.Sizes {
+		result := s.result()
+		if s.HeuristicTimedOut {
+			result += " (sorted heuristic timed out)"
+		}
+
+		fmt.Fprintf(tw, "%d×%d\t%d\t%d\t%d\t%d\t%d\t%v\t%s\n", s.Size, s.Size,
+			s.Nodes, s.Placements, s.Backtracks, s.Pruned, s.MaxDepth, s.Elapsed.Round(time.Microsecond), result)
+	}
+
+	total := stats.Total()
+	fmt.Fprintf(tw, "total\t%d\t%d\t%d\t%d\t%d\t%v\t%s\n", total.Nodes, total.Placements,
+		total.Backtracks, total.Pruned, total.MaxDepth, stats.Elapsed.Round(time.Microsecond), solverName)
+	tw.Flush()
+
+	return sb.String()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tetris-optimizer/solver"
+)
+
+func TestStatsTotal(t *testing.T) {
+	stats := Stats{Sizes: []SizeStats{
+		{Size: 4, SearchStats: solver.SearchStats{Nodes: 10, Placements: 9, Backtracks: 9, MaxDepth: 3}},
+		{Size: 5, SearchStats: solver.SearchStats{Nodes: 5, Placements: 4, MaxDepth: 4, Strategy: "dlx"}, Solved: true},
+	}}
+
+	expected := solver.SearchStats{Nodes: 15, Placements: 13, Backtracks: 9, MaxDepth: 4, Strategy: "dlx"}
+	if total := stats.Total(); total != expected {
+		t.Errorf("expected %+v, got %+v", expected, total)
+	}
+}
+
+func TestFormatStats(t *testing.T) {
+	stats := Stats{
+		Sizes: []SizeStats{
+			{Size: 4, SearchStats: solver.SearchStats{Nodes: 10, Pruned: 3, HeuristicTimedOut: true}, Elapsed: time.Millisecond},
+			{Size: 5, SearchStats: solver.SearchStats{Nodes: 5, Strategy: "backtrack"}, Solved: true},
+			{Size: 6, Interrupted: true},
+		},
+		Elapsed: 2 * time.Millisecond,
+	}
+
+	lines := strings.Split(strings.TrimSuffix(formatStats(stats, "hybrid"), "\n"), "\n")
+	expected := []string{
+		"SIZE",
+		"4×4    10     0           0           3       0          1ms   infeasible (sorted heuristic timed out)",
+		"5×5    5      0           0           0       0          0s    solved by backtrack",
+		"6×6    0      0           0           0       0          0s    interrupted",
+		"total  15     0           0           3       0          2ms   hybrid",
+	}
+
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got:\n%s", len(expected), strings.Join(lines, "\n"))
+	}
+
+	for i, line := range lines {
+		if !strings.HasPrefix(line, expected[i]) {
+			t.Errorf("expected line %d to start with %q, got %q", i, expected[i], line)
+		}
+	}
+}
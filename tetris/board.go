@@ -1,43 +1,154 @@
 package tetris
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
 	"slices"
+	"strconv"
 	"strings"
 )
 
-// Board is a square grid for placing tetrominoes.
+// MaxSize is the widest board supported by the 64-bit row masks.
+const MaxSize = 64
+
+// Board is a rectangular grid for placing tetrominoes, with optional blocked
+// cells that no piece may cover.
+//
+// Occupancy is tracked as one bitmask per row so that collision checks are a
+// few AND operations. Blocked cells are set in the occupancy rows as well.
+// Piece IDs are kept in a separate layer that is only read when rendering the board.
 type Board struct {
-	Size  int // Width and height of the square board
-	board [][]byte
+	Width   int
+	Height  int
+	rows    []uint64 // Bit x of rows[y] is set when cell (x, y) is occupied or blocked
+	blocked []uint64 // Bit x of blocked[y] is set when cell (x, y) is outside the container
+	board   [][]int  // ID layer, only meaningful where a piece occupies the cell
 }
 
 // NewBoard creates a new empty square board.
+// It panics if size exceeds MaxSize.
 func NewBoard(size uint) Board {
+	return NewRectBoard(size, size)
+}
+
+// NewRectBoard creates a new empty board of the given width and height.
+// It panics if either dimension exceeds MaxSize.
+func NewRectBoard(width, height uint) Board {
+	if width > MaxSize || height > MaxSize {
+		panic(fmt.Sprintf("tetris: board size %d×%d exceeds maximum of %d", width, height, MaxSize))
+	}
+
 	b := Board{
-		Size:  int(size),
-		board: make([][]byte, size),
+		Width:   int(width),
+		Height:  int(height),
+		rows:    make([]uint64, height),
+		blocked: make([]uint64, height),
+		board:   make([][]int, height),
 	}
 
 	// OPTIMISATION: Allocating all the board memory in one continuous block
 	// improves cache locality
-	backingMem := slices.Repeat([]byte{'.'}, b.Size*b.Size)
+	backingMem := make([]int, b.Width*b.Height)
 
-	for i := range b.Size {
-		b.board[i] = backingMem[i*b.Size : (i+1)*b.Size]
+	for i := range b.Height {
+		b.board[i] = backingMem[i*b.Width : (i+1)*b.Width]
 	}
 
 	return b
 }
 
+// Clone returns a deep copy of the board.
+func (b Board) Clone() Board {
+	c := NewRectBoard(uint(b.Width), uint(b.Height))
+
+	copy(c.rows, b.rows)
+	copy(c.blocked, b.blocked)
+	for i, row := range b.board {
+		copy(c.board[i], row)
+	}
+
+	return c
+}
+
+// Block marks cell (x, y) as outside the container so no piece may cover it.
+// It panics if the cell is out of range.
+func (b *Board) Block(x, y int) {
+	if x < 0 || x >= b.Width {
+		panic(fmt.Sprintf("tetris: column %d out of range", x))
+	}
+
+	b.rows[y] |= 1 << x
+	b.blocked[y] |= 1 << x
+}
+
+// Blocked reports whether cell (x, y) is outside the container.
+func (b Board) Blocked(x, y int) bool {
+	return b.blocked[y]&(1<<x) != 0
+}
+
+// Empty reports whether cell (x, y) lies on the board and is neither covered nor blocked.
+func (b Board) Empty(x, y int) bool {
+	return x >= 0 && y >= 0 && x < b.Width && y < b.Height && b.rows[y]&(1<<x) == 0
+}
+
+// Filled returns the number of cells covered by pieces.
+func (b Board) Filled() int {
+	count := 0
+
+	for y, row := range b.rows {
+		count += bits.OnesCount64(row &^ b.blocked[y])
+	}
+
+	return count
+}
+
+// Free returns the number of cells pieces may cover on an empty board.
+func (b Board) Free() int {
+	count := b.Width * b.Height
+
+	for _, row := range b.blocked {
+		count -= bits.OnesCount64(row)
+	}
+
+	return count
+}
+
+// FreeByParity returns the number of cells that are neither covered nor blocked,
+// split by the colour of a checkerboard: cells with x+y even, then odd.
+func (b Board) FreeByParity() (even, odd int) {
+	const evenColumns = 0x5555555555555555 // Bits 0, 2, 4, ...
+
+	full := ^uint64(0) >> (MaxSize - b.Width)
+
+	for y, row := range b.rows {
+		free := ^row & full
+
+		mask := uint64(evenColumns)
+		if y%2 == 1 {
+			mask = ^mask
+		}
+
+		even += bits.OnesCount64(free & mask)
+		odd += bits.OnesCount64(free &^ mask)
+	}
+
+	return even, odd
+}
+
 // CanPlace checks if a piece fits at the given position.
 func (b *Board) CanPlace(tet Piece, x, y int) bool {
-	if x+tet.Width > b.Size || y+tet.Height > b.Size {
+	if x+tet.Width > b.Width || y+tet.Height > b.Height {
 		return false
 	}
 
+	masks := tet.rowMasks()
+
 	// Check that all cells where the piece would occupy are empty
-	for _, p := range tet.Pos {
-		if b.board[y+p.Y][x+p.X] != '.' {
+	for i := range tet.Height {
+		if b.rows[y+i]&(masks[i]<<x) != 0 {
 			return false
 		}
 	}
@@ -47,25 +158,53 @@ func (b *Board) CanPlace(tet Piece, x, y int) bool {
 
 // Place places a piece on the board.
 func (b *Board) Place(tet Piece, x int, y int) {
+	masks := tet.rowMasks()
+
+	for i := range tet.Height {
+		b.rows[y+i] |= masks[i] << x
+	}
+
 	for _, p := range tet.Pos {
 		b.board[y+p.Y][x+p.X] = tet.ID
 	}
 }
 
 // Remove clears a piece from the board (used for backtracking).
+// The ID layer is left untouched as it is masked by the occupancy rows.
 func (b *Board) Remove(tet Piece, x, y int) {
-	for _, p := range tet.Pos {
-		b.board[y+p.Y][x+p.X] = '.'
+	masks := tet.rowMasks()
+
+	for i := range tet.Height {
+		b.rows[y+i] &^= masks[i] << x
 	}
 }
 
-// ToString returns a string representation of the board.
+// ToString returns a string representation of the board, with pieces
+// labelled from DefaultAlphabet.
 func (b Board) ToString() string {
+	return b.Format(DefaultAlphabet)
+}
+
+// Format returns a string representation of the board, with each piece
+// labelled by the character of alphabet at its ID. Pieces outside the
+// alphabet are shown as '?' and blocked cells as '#'.
+func (b Board) Format(alphabet string) string {
 	var str strings.Builder
 
-	for _, row := range b.board {
-		for _, r := range row {
-			str.WriteByte(r)
+	labels := []rune(alphabet)
+
+	for y, row := range b.board {
+		for x, id := range row {
+			switch {
+			case b.blocked[y]&(1<<x) != 0:
+				str.WriteByte('#')
+			case b.rows[y]&(1<<x) == 0:
+				str.WriteByte('.')
+			case id >= 0 && id < len(labels):
+				str.WriteRune(labels[id])
+			default:
+				str.WriteByte('?')
+			}
 		}
 
 		str.WriteRune('\n')
@@ -73,3 +212,188 @@ func (b Board) ToString() string {
 
 	return str.String()
 }
+
+// FormatNumeric returns a string representation of the board with each
+// piece shown by its numeric ID. Cells are right-aligned to the widest ID
+// and separated by spaces, so any number of pieces can be represented.
+// Empty cells are shown as '.' and blocked cells as '#'.
+func (b Board) FormatNumeric() string {
+	var str strings.Builder
+
+	width := 1
+	for y, row := range b.board {
+		for x, id := range row {
+			if b.rows[y]&^b.blocked[y]&(1<<x) != 0 {
+				width = max(width, len(strconv.Itoa(id)))
+			}
+		}
+	}
+
+	for y, row := range b.board {
+		for x, id := range row {
+			if x > 0 {
+				str.WriteByte(' ')
+			}
+
+			cell := "."
+			switch {
+			case b.blocked[y]&(1<<x) != 0:
+				cell = "#"
+			case b.rows[y]&(1<<x) != 0:
+				cell = strconv.Itoa(id)
+			}
+
+			fmt.Fprintf(&str, "%*s", width, cell)
+		}
+
+		str.WriteRune('\n')
+	}
+
+	return str.String()
+}
+
+// ParseBoard reads a square board in the format printed by ToString.
+func ParseBoard(r io.Reader) (Board, error) {
+	return ParseBoardAlphabet(r, DefaultAlphabet)
+}
+
+// ParseBoardAlphabet reads a square board in the format printed by Format with
+// the same alphabet: '.' for empty cells, '#' for blocked cells and the label
+// of each piece ID otherwise. Trailing blank lines are ignored.
+func ParseBoardAlphabet(r io.Reader, alphabet string) (Board, error) {
+	labels := []rune(alphabet)
+	scanner := bufio.NewScanner(r)
+
+	var rows [][]rune
+	blank := false
+
+	for scanner.Scan() {
+		row := []rune(scanner.Text())
+
+		if len(row) == 0 {
+			blank = true
+			continue
+		}
+
+		if blank {
+			return Board{}, errors.New("invalid board; board should not contain blank lines")
+		}
+
+		if len(rows) > 0 && len(row) != len(rows[0]) {
+			return Board{}, errors.New("invalid board; rows should have equal length")
+		}
+
+		rows = append(rows, row)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Board{}, err
+	}
+
+	if len(rows) == 0 {
+		return Board{}, errors.New("invalid board; board should not be empty")
+	}
+
+	if len(rows) != len(rows[0]) {
+		return Board{}, errors.New("invalid board; board should be square")
+	}
+
+	if len(rows) > MaxSize {
+		return Board{}, fmt.Errorf("invalid board; board should be at most %d cells wide", MaxSize)
+	}
+
+	b := NewBoard(uint(len(rows)))
+
+	for y, row := range rows {
+		for x, label := range row {
+			switch label {
+			case '.':
+				continue
+			case '#':
+				b.Block(x, y)
+				continue
+			}
+
+			id := slices.Index(labels, label)
+			if id < 0 {
+				return Board{}, fmt.Errorf("invalid board; unknown label '%c'", label)
+			}
+
+			b.rows[y] |= 1 << x
+			b.board[y][x] = id
+		}
+	}
+
+	return b, nil
+}
+
+// Cells returns the cells covered by each piece ID, row by row.
+func (b Board) Cells() map[int][]Point {
+	cells := map[int][]Point{}
+
+	for y, row := range b.board {
+		for x, id := range row {
+			if b.rows[y]&^b.blocked[y]&(1<<x) != 0 {
+				cells[id] = append(cells[id], Point{X: x, Y: y})
+			}
+		}
+	}
+
+	return cells
+}
+
+// Relabel swaps the IDs of identical pieces so that within each group of
+// IdenticalGroups, the pieces on the board follow reading order by their first
+// cell, lowest ID first. The board still holds the same solution.
+func (b *Board) Relabel(pieces []Piece) {
+	cells := b.Cells()
+	ids := map[int]int{}
+
+	for _, group := range IdenticalGroups(pieces) {
+		var placed []int
+
+		for _, i := range group {
+			if _, ok := cells[pieces[i].ID]; ok {
+				placed = append(placed, pieces[i].ID)
+			}
+		}
+
+		order := slices.Clone(placed)
+		slices.Sort(placed)
+		slices.SortFunc(order, func(a, b int) int { return comparePoints(cells[a][0], cells[b][0]) })
+
+		for k, id := range order {
+			ids[id] = placed[k]
+		}
+	}
+
+	for y, row := range b.board {
+		for x, id := range row {
+			if newID, ok := ids[id]; ok && b.rows[y]&^b.blocked[y]&(1<<x) != 0 {
+				row[x] = newID
+			}
+		}
+	}
+}
+
+// Equal reports whether both boards have the same shape, blocked cells and pieces.
+// IDs left behind by Remove are ignored.
+func (b Board) Equal(other Board) bool {
+	if b.Width != other.Width || b.Height != other.Height {
+		return false
+	}
+
+	if !slices.Equal(b.rows, other.rows) || !slices.Equal(b.blocked, other.blocked) {
+		return false
+	}
+
+	for y, row := range b.board {
+		for x, id := range row {
+			if b.rows[y]&^b.blocked[y]&(1<<x) != 0 && other.board[y][x] != id {
+				return false
+			}
+		}
+	}
+
+	return true
+}
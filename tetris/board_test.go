@@ -1,31 +1,112 @@
 package tetris
 
 import (
+	"slices"
+	"strings"
 	"testing"
 )
 
 func TestNewBoard(t *testing.T) {
 	board := NewBoard(5)
 
-	if board.Size != 5 {
-		t.Errorf("expected size 5, got %d", board.Size)
+	if board.Width != 5 || board.Height != 5 {
+		t.Errorf("expected size 5×5, got %d×%d", board.Width, board.Height)
 	}
 
 	// Verify all cells are empty
-	for y := range board.Size {
-		for x := range board.Size {
-			if board.board[y][x] != '.' {
-				t.Errorf("expected empty cell at (%d,%d), got %c", x, y, board.board[y][x])
+	for y := range board.Height {
+		for x := range board.Width {
+			if board.rows[y]&(1<<x) != 0 {
+				t.Errorf("expected empty cell at (%d,%d)", x, y)
 			}
 		}
 	}
 }
 
+func TestNewRectBoard(t *testing.T) {
+	board := NewRectBoard(5, 2)
+
+	if board.Width != 5 || board.Height != 2 {
+		t.Fatalf("expected size 5×2, got %d×%d", board.Width, board.Height)
+	}
+
+	if output := board.ToString(); output != ".....\n.....\n" {
+		t.Fatalf("expected empty 5×2 board, got:\n%s", output)
+	}
+
+	testData := []struct {
+		name     string
+		x, y     int
+		expected bool
+	}{
+		{"valid left", 0, 0, true},
+		{"valid right", 3, 0, true},
+		{"invalid out-of-bounds x", 4, 0, false},
+		{"invalid out-of-bounds y", 0, 1, false},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got := board.CanPlace(OPiece, test.x, test.y)
+			if got != test.expected {
+				t.Errorf("canPlace(%d, %d) = %v, want %v", test.x, test.y, got, test.expected)
+			}
+		})
+	}
+}
+
+func TestBlock(t *testing.T) {
+	board := NewRectBoard(4, 3)
+
+	board.Block(0, 0)
+	board.Block(3, 2)
+
+	if !board.Blocked(0, 0) || board.Blocked(1, 0) {
+		t.Fatal("expected only the blocked cells to be reported as blocked")
+	}
+
+	if board.Free() != 10 {
+		t.Fatalf("expected 10 free cells, got %d", board.Free())
+	}
+
+	if board.CanPlace(OPiece, 0, 0) || board.CanPlace(OPiece, 2, 1) {
+		t.Fatal("expected placements over blocked cells to fail")
+	}
+
+	board.Place(OPiece, 1, 0)
+	board.Remove(OPiece, 1, 0)
+	board.Place(OPiece, 1, 1)
+
+	if board.Filled() != 4 {
+		t.Fatalf("expected 4 filled cells, got %d", board.Filled())
+	}
+
+	if !board.Clone().Blocked(3, 2) {
+		t.Fatal("expected clone to keep blocked cells")
+	}
+
+	expected := "#...\n" +
+		".AA.\n" +
+		".AA#\n"
+
+	if output := board.ToString(); output != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, output)
+	}
+
+	expected = "# . . .\n" +
+		". 0 0 .\n" +
+		". 0 0 #\n"
+
+	if output := board.FormatNumeric(); output != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, output)
+	}
+}
+
 var OPiece = Piece{
-	Pos:    [4]Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}},
+	Pos:    []Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}},
 	Width:  2,
 	Height: 2,
-	ID:     'A',
+	ID:     0,
 }
 
 func TestRemove(t *testing.T) {
@@ -78,3 +159,371 @@ func TestToString(t *testing.T) {
 		t.Fatalf("expected:\n%s\ngot:\n%s", expected, output)
 	}
 }
+
+func TestCanPlaceOccupied(t *testing.T) {
+	board := NewBoard(5)
+
+	board.Place(OPiece, 0, 0)
+	testData := []struct {
+		name     string
+		x, y     int
+		expected bool
+	}{
+		{"full overlap", 0, 0, false},
+		{"partial overlap right", 1, 0, false},
+		{"partial overlap diagonal", 1, 1, false},
+		{"adjacent right", 2, 0, true},
+		{"adjacent below", 0, 2, true},
+		{"far corner", 3, 3, true},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got := board.CanPlace(OPiece, test.x, test.y)
+			if got != test.expected {
+				t.Errorf("canPlace(%d, %d) = %v, want %v", test.x, test.y, got, test.expected)
+			}
+		})
+	}
+}
+
+func TestToStringAfterRemove(t *testing.T) {
+	board := NewBoard(2)
+
+	board.Place(OPiece, 0, 0)
+	board.Remove(OPiece, 0, 0)
+
+	if output := board.ToString(); output != "..\n..\n" {
+		t.Fatalf("expected empty board, got:\n%s", output)
+	}
+}
+
+func TestClone(t *testing.T) {
+	board := NewBoard(4)
+
+	board.Place(OPiece, 0, 0)
+	clone := board.Clone()
+	clone.Remove(OPiece, 0, 0)
+
+	if board.CanPlace(OPiece, 0, 0) {
+		t.Fatal("expected original board to keep its piece after clone was modified")
+	}
+
+	if clone.ToString() != NewBoard(4).ToString() {
+		t.Fatalf("expected empty clone, got:\n%s", clone.ToString())
+	}
+}
+
+func TestFilled(t *testing.T) {
+	board := NewBoard(4)
+
+	if board.Filled() != 0 {
+		t.Fatalf("expected empty board, got %d filled cells", board.Filled())
+	}
+
+	board.Place(OPiece, 0, 0)
+	board.Place(OPiece, 2, 2)
+
+	if board.Filled() != 8 {
+		t.Fatalf("expected 8 filled cells, got %d", board.Filled())
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	board := NewRectBoard(3, 2)
+	board.Block(2, 1)
+	board.Place(OPiece, 0, 0)
+
+	testData := []struct {
+		name     string
+		x, y     int
+		expected bool
+	}{
+		{"Empty", 2, 0, true},
+		{"Covered", 1, 1, false},
+		{"Blocked", 2, 1, false},
+		{"Left of the board", -1, 0, false},
+		{"Right of the board", 3, 0, false},
+		{"Below the board", 0, 2, false},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			if got := board.Empty(test.x, test.y); got != test.expected {
+				t.Errorf("Empty(%d, %d) = %v, want %v", test.x, test.y, got, test.expected)
+			}
+		})
+	}
+}
+
+func TestFreeByParity(t *testing.T) {
+	testData := []struct {
+		name      string
+		board     func() Board
+		even, odd int
+	}{
+		{"Empty even square", func() Board { return NewBoard(4) }, 8, 8},
+		{"Empty odd square", func() Board { return NewBoard(5) }, 13, 12},
+		{"Full width", func() Board { return NewRectBoard(MaxSize, 2) }, MaxSize, MaxSize},
+		{
+			"Blocked and covered cells", func() Board {
+				b := NewRectBoard(3, 2)
+				b.Block(2, 1)
+				b.Place(OPiece, 0, 0)
+				return b
+			}, 1, 0,
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			even, odd := test.board().FreeByParity()
+			if even != test.even || odd != test.odd {
+				t.Errorf("expected %d even and %d odd free cells, got %d and %d", test.even, test.odd, even, odd)
+			}
+		})
+	}
+}
+
+func TestPlacePolyomino(t *testing.T) {
+	// Vertical I-pentomino, taller than any tetromino.
+	pentomino := Piece{
+		Pos:    []Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: 2}, {X: 0, Y: 3}, {X: 0, Y: 4}},
+		Width:  1,
+		Height: 5,
+		ID:     0,
+	}
+	board := NewBoard(5)
+
+	if board.CanPlace(pentomino, 0, 1) {
+		t.Fatal("expected placement past the bottom edge to fail")
+	}
+
+	board.Place(pentomino, 2, 0)
+	expected := "..A..\n" +
+		"..A..\n" +
+		"..A..\n" +
+		"..A..\n" +
+		"..A..\n"
+
+	if output := board.ToString(); output != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, output)
+	}
+
+	if board.CanPlace(pentomino, 2, 0) {
+		t.Fatal("expected placement over the pentomino to fail")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	board := NewBoard(3)
+	piece := OPiece
+
+	piece.ID = 27
+	board.Place(piece, 0, 0)
+	piece.ID = 70
+	board.Place(piece, 1, 1)
+
+	t.Run("Default alphabet", func(t *testing.T) {
+		expected := "bb.\n" +
+			"b??\n" +
+			".??\n"
+
+		if output := board.ToString(); output != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, output)
+		}
+	})
+
+	t.Run("Custom alphabet", func(t *testing.T) {
+		labels := strings.Repeat("x", 27) + "ö" + strings.Repeat("y", 42) + "é"
+		expected := "öö.\n" +
+			"öéé\n" +
+			".éé\n"
+
+		if output := board.Format(labels); output != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, output)
+		}
+	})
+
+	t.Run("Numeric", func(t *testing.T) {
+		expected := "27 27  .\n" +
+			"27 70 70\n" +
+			" . 70 70\n"
+
+		if output := board.FormatNumeric(); output != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, output)
+		}
+	})
+}
+
+func TestParseBoard(t *testing.T) {
+	testData := []struct {
+		name        string
+		input       string
+		expected    string
+		expectError bool
+		errMsg      string
+	}{
+		{"Empty cells", "...\n...\n...\n", "...\n...\n...\n", false, ""},
+		{"Pieces", "AAB.\nAAB.\n.CB.\nCCB.\n", "AAB.\nAAB.\n.CB.\nCCB.\n", false, ""},
+		{"Blocked cells and trailing blank line", "#a\nz#\n\n", "#a\nz#\n", false, ""},
+		{"No final newline", "AA\nAA", "AA\nAA\n", false, ""},
+		{"Empty", "", "", true, "invalid board; board should not be empty"},
+		{"Ragged", "AA.\nAA\n...\n", "", true, "invalid board; rows should have equal length"},
+		{"Not square", "AA..\nAA..\n", "", true, "invalid board; board should be square"},
+		{"Blank line inside", "..\n\n..\n", "", true, "invalid board; board should not contain blank lines"},
+		{"Unknown label", "A*\n..\n", "", true, "invalid board; unknown label '*'"},
+		{
+			"Too large", strings.Repeat(strings.Repeat(".", 65)+"\n", 65), "", true,
+			"invalid board; board should be at most 64 cells wide",
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			board, err := ParseBoard(strings.NewReader(test.input))
+
+			if test.expectError {
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+
+				if err.Error() != test.errMsg {
+					t.Errorf("expected error %q, got %q", test.errMsg, err.Error())
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if output := board.ToString(); output != test.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", test.expected, output)
+			}
+		})
+	}
+
+	t.Run("Round trip", func(t *testing.T) {
+		board := NewBoard(4)
+		board.Block(3, 3)
+		board.Place(OPiece, 0, 0)
+		piece := OPiece
+		piece.ID = 53
+		board.Place(piece, 2, 1)
+
+		parsed, err := ParseBoard(strings.NewReader(board.ToString()))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !parsed.Equal(board) {
+			t.Fatalf("expected:\n%s\ngot:\n%s", board.ToString(), parsed.ToString())
+		}
+	})
+
+	t.Run("Custom alphabet", func(t *testing.T) {
+		board, err := ParseBoardAlphabet(strings.NewReader("αβ\n.β\n"), "αβ")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if output := board.FormatNumeric(); output != "0 1\n. 1\n" {
+			t.Fatalf("expected numeric IDs, got:\n%s", output)
+		}
+	})
+}
+
+func TestCells(t *testing.T) {
+	board := NewBoard(4)
+	board.Block(3, 0)
+	board.Place(OPiece, 0, 0)
+	piece := OPiece
+	piece.ID = 1
+	board.Place(piece, 2, 2)
+	board.Remove(piece, 2, 2)
+
+	cells := board.Cells()
+	expected := []Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 1}}
+
+	if len(cells) != 1 || !slices.Equal(cells[0], expected) {
+		t.Fatalf("expected only piece 0 at %v, got %v", expected, cells)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	board := NewBoard(4)
+	board.Place(OPiece, 0, 0)
+
+	removed := board.Clone()
+	piece := OPiece
+	piece.ID = 5
+	removed.Place(piece, 2, 2)
+	removed.Remove(piece, 2, 2)
+
+	blocked := board.Clone()
+	blocked.Block(3, 3)
+
+	relabelled := NewBoard(4)
+	relabelled.Place(piece, 0, 0)
+
+	testData := []struct {
+		name     string
+		other    Board
+		expected bool
+	}{
+		{"Clone", board.Clone(), true},
+		{"Stale IDs after remove", removed, true},
+		{"Blocked cell", blocked, false},
+		{"Different ID", relabelled, false},
+		{"Different size", NewBoard(5), false},
+		{"Different shape", NewRectBoard(4, 3), false},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			if got := board.Equal(test.other); got != test.expected {
+				t.Errorf("Equal() = %v, want %v", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestRelabel(t *testing.T) {
+	square := func(id int) Piece {
+		p := OPiece
+		p.ID = id
+		return p
+	}
+
+	i := Piece{Pos: []Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}, {X: 3, Y: 0}}, Width: 4, Height: 1, ID: 1}
+	pieces := []Piece{square(0), i, square(2), square(3)}
+
+	board := NewRectBoard(4, 5)
+	board.Place(square(3), 0, 0)
+	board.Place(square(0), 2, 0)
+	board.Place(i, 0, 2)
+	board.Place(square(2), 0, 3)
+	board.Remove(square(2), 0, 3) // A piece left off the board keeps its ID
+	board.Relabel(pieces)
+
+	expected := "AADD\nAADD\nBBBB\n....\n....\n"
+	if output := board.ToString(); output != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, output)
+	}
+}
+
+func BenchmarkCanPlace(b *testing.B) {
+	board := NewBoard(8)
+	board.Place(OPiece, 2, 2)
+	board.Place(OPiece, 5, 5)
+
+	for b.Loop() {
+		for y := range 7 {
+			for x := range 7 {
+				board.CanPlace(OPiece, x, y)
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package tetris contains the validation errors of Init and InitPolyomino.
+package tetris
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors for errors.Is. The typed errors below wrap the last three.
+var (
+	ErrGridSize     = errors.New("tetromino should be a 4×4 grid")
+	ErrGlyph        = errors.New("unrecognised character")
+	ErrBlockCount   = errors.New("wrong number of blocks")
+	ErrDisconnected = errors.New("blocks are not connected")
+)
+
+// Kinds of piece named in validation errors.
+const (
+	KindTetromino = "tetromino"
+	KindPolyomino = "polyomino"
+)
+
+// GlyphError reports a character other than '#' or '.' in a piece.
+type GlyphError struct {
+	Glyph byte
+	Pos   Point // Position of the character in the raw grid
+}
+
+// Error implements error.
+func (e *GlyphError) Error() string {
+	return fmt.Sprintf("unrecognised character '%c'", e.Glyph)
+}
+
+// Unwrap returns ErrGlyph.
+func (e *GlyphError) Unwrap() error {
+	return ErrGlyph
+}
+
+// BlockCountError reports a tetromino without exactly 4 blocks, or a polyomino without any.
+type BlockCountError struct {
+	Kind  string // KindTetromino or KindPolyomino
+	Count int    // Number of blocks found
+}
+
+// Error implements error. Tetrominoes with too many blocks keep their historical
+// trailing period so that scripts matching the message still work.
+func (e *BlockCountError) Error() string {
+	switch {
+	case e.Kind == KindPolyomino:
+		return "polyomino should have at least 1 block"
+	case e.Count > 4:
+		return "tetromino should have 4 blocks."
+	default:
+		return "tetromino should have 4 blocks"
+	}
+}
+
+// Unwrap returns ErrBlockCount.
+func (e *BlockCountError) Unwrap() error {
+	return ErrBlockCount
+}
+
+// DisconnectedError reports a piece whose blocks do not form one orthogonally connected shape.
+type DisconnectedError struct {
+	Kind  string  // KindTetromino or KindPolyomino
+	Stray []Point // Blocks not connected to the first block, row by row in the raw grid
+}
+
+// Error implements error.
+func (e *DisconnectedError) Error() string {
+	return "invalid " + e.Kind
+}
+
+// Unwrap returns ErrDisconnected.
+func (e *DisconnectedError) Unwrap() error {
+	return ErrDisconnected
+}
@@ -0,0 +1,48 @@
+package tetris
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorMessages(t *testing.T) {
+	testData := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"Glyph", &GlyphError{Glyph: 'x', Pos: Point{1, 2}}, "unrecognised character 'x'"},
+		{"Too few blocks", &BlockCountError{Kind: KindTetromino, Count: 3}, "tetromino should have 4 blocks"},
+		{"Too many blocks", &BlockCountError{Kind: KindTetromino, Count: 5}, "tetromino should have 4 blocks."},
+		{"No blocks", &BlockCountError{Kind: KindPolyomino}, "polyomino should have at least 1 block"},
+		{"Disconnected tetromino", &DisconnectedError{Kind: KindTetromino}, "invalid tetromino"},
+		{"Disconnected polyomino", &DisconnectedError{Kind: KindPolyomino}, "invalid polyomino"},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.err.Error(); got != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestErrorsAs(t *testing.T) {
+	_, err := Init(makeGrid("##..", "#.x.", "....", "...."), 0)
+	err = fmt.Errorf("piece 3: %w", err)
+
+	var glyphErr *GlyphError
+	if !errors.As(err, &glyphErr) {
+		t.Fatalf("expected a *GlyphError, got %v", err)
+	}
+
+	if glyphErr.Pos != (Point{2, 1}) {
+		t.Errorf("expected the glyph at (2, 1), got %v", glyphErr.Pos)
+	}
+
+	if !errors.Is(err, ErrGlyph) || errors.Is(err, ErrBlockCount) {
+		t.Errorf("expected the error to match only ErrGlyph, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package tetris
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// DefaultAlphabet labels the first 62 pieces A-Z, then a-z, then 0-9.
+const DefaultAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// Labels returns the label of each piece ID in alphabet.
+// It reports an error if the alphabet is empty, repeats a label or uses '.'
+// or '#', which mark empty and blocked cells, or a space or control character,
+// which could not be read back from the printed board.
+func Labels(alphabet string) ([]rune, error) {
+	labels := []rune(alphabet)
+	if len(labels) == 0 {
+		return nil, errors.New("alphabet should not be empty")
+	}
+
+	seen := map[rune]bool{}
+
+	for _, r := range labels {
+		if r == '.' || r == '#' {
+			return nil, fmt.Errorf("alphabet should not contain '%c'", r)
+		}
+
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return nil, fmt.Errorf("alphabet should not contain %q", r)
+		}
+
+		if seen[r] {
+			return nil, fmt.Errorf("alphabet repeats the label '%c'", r)
+		}
+
+		seen[r] = true
+	}
+
+	return labels, nil
+}
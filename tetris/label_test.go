@@ -0,0 +1,49 @@
+package tetris
+
+import (
+	"testing"
+)
+
+func TestLabels(t *testing.T) {
+	testData := []struct {
+		name      string
+		alphabet  string
+		expected  int
+		expectErr bool
+		errMsg    string
+	}{
+		{"Default", DefaultAlphabet, 62, false, ""},
+		{"Unicode", "αβγ", 3, false, ""},
+		{"Repeated label", "ABCA", 0, true, "alphabet repeats the label 'A'"},
+		{"Empty marker", "AB.", 0, true, "alphabet should not contain '.'"},
+		{"Blocked marker", "#AB", 0, true, "alphabet should not contain '#'"},
+		{"Empty", "", 0, true, "alphabet should not be empty"},
+		{"Space", "AB C", 0, true, "alphabet should not contain ' '"},
+		{"Tab", "AB\tC", 0, true, `alphabet should not contain '\t'`},
+		{"Control character", "AB\x00", 0, true, `alphabet should not contain '\x00'`},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			labels, err := Labels(test.alphabet)
+
+			if test.expectErr {
+				if err == nil {
+					t.Error("expected error but got nil")
+				} else if err.Error() != test.errMsg {
+					t.Errorf("expected error %q, got %q", test.errMsg, err.Error())
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(labels) != test.expected {
+				t.Errorf("expected %d labels, got %d", test.expected, len(labels))
+			}
+		})
+	}
+}
@@ -1,55 +1,111 @@
-// Package tetris contains core data structures and validation logic for tetrominoes and the board.
+// Package tetris contains core data structures and validation logic for polyominoes and the board.
 package tetris
 
 import (
-	"errors"
+	"cmp"
 	"fmt"
+	"slices"
 )
 
-// RawPiece is an unvalidated 4×4 tetromino grid.
-type RawPiece [4][4]byte
+// RawPiece is an unvalidated grid of rows, 4×4 for a tetromino.
+type RawPiece [][]byte
 
 // Point represents a 2D coordinate (0-indexed, origin at top-left).
 type Point struct {
 	X, Y int
 }
 
-// Piece is a validated, normalized tetromino with blocks, dimensions, and ID.
+// Transform is a set of symmetries a piece may be placed under.
+type Transform uint8
+
+const (
+	Rotate Transform = 1 << iota // Quarter turns
+	Mirror                       // Reflection across the vertical axis
+)
+
+// Piece is a validated, normalized polyomino with blocks, dimensions, and ID.
 type Piece struct {
-	Width  int
-	Height int
-	ID     byte     // Character to print (A, B, C, ...)
-	Pos    [4]Point // Relative coordinates of the 4 blocks
+	Width      int
+	Height     int
+	ID         int       // Index of the piece, labelled A, B, C, ... when printed
+	Pos        []Point   // Relative coordinates of the blocks
+	Transforms Transform // Symmetries allowed when placing the piece
+	masks      []uint64  // Precomputed occupancy bitmask of each row
 }
 
 //////////////////// STATIC FUNCTIONS ////////////////////
 
-// countNeighbors returns the count of orthogonally adjacent blocks.
-func countNeighbors(pos Point, tet RawPiece) int {
-	neighbors := 0
+// scanBlocks returns the coordinates of the '#' cells of a grid, row by row.
+func scanBlocks(raw RawPiece) ([]Point, error) {
+	var blocks []Point
+
+	for y, row := range raw {
+		for x, char := range row {
+			if char == '.' {
+				continue
+			}
+
+			if char != '#' {
+				return nil, &GlyphError{Glyph: char, Pos: Point{X: x, Y: y}}
+			}
 
-	if pos.X > 0 && tet[pos.Y][pos.X-1] == '#' { // check left
-		neighbors++
+			blocks = append(blocks, Point{X: x, Y: y})
+		}
 	}
 
-	if pos.X < len(tet[pos.Y])-1 && tet[pos.Y][pos.X+1] == '#' { // check right
-		neighbors++
+	return blocks, nil
+}
+
+// strayBlocks returns the blocks that are not orthogonally connected to the first one,
+// in their original order. It returns nil for a connected shape.
+func strayBlocks(blocks []Point) []Point {
+	if len(blocks) == 0 {
+		return nil
 	}
 
-	if pos.Y > 0 && tet[pos.Y-1][pos.X] == '#' { // check down
-		neighbors++
+	unvisited := make(map[Point]bool, len(blocks))
+	for _, p := range blocks {
+		unvisited[p] = true
 	}
 
-	if pos.Y < len(tet)-1 && tet[pos.Y+1][pos.X] == '#' { // check up
-		neighbors++
+	// Flood fill from the first block.
+	stack := []Point{blocks[0]}
+	delete(unvisited, blocks[0])
+
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, n := range []Point{{p.X - 1, p.Y}, {p.X + 1, p.Y}, {p.X, p.Y - 1}, {p.X, p.Y + 1}} {
+			if unvisited[n] {
+				delete(unvisited, n)
+				stack = append(stack, n)
+			}
+		}
 	}
 
-	return neighbors
+	var stray []Point
+
+	for _, p := range blocks {
+		if unvisited[p] {
+			stray = append(stray, p)
+		}
+	}
+
+	return stray
+}
+
+// newPiece builds a normalized piece from connected blocks.
+func newPiece(blocks []Point, id int) Piece {
+	tet := Piece{ID: id, Pos: blocks}
+
+	tet.normalize()
+	return tet
 }
 
 //////////////////// PRIVATE METHODS ////////////////////
 
-// normalize shifts the tetromino to start at (0,0) and calculates bounds.
+// normalize shifts the piece to start at (0,0) and calculates bounds.
 func (t *Piece) normalize() {
 	// Find minimum X and Y coordinates
 	minX, minY := t.Pos[0].X, t.Pos[0].Y
@@ -79,46 +135,161 @@ func (t *Piece) normalize() {
 
 	t.Width = maxX + 1
 	t.Height = maxY + 1
+	t.masks = t.computeMasks()
+}
+
+// computeMasks builds the row bitmasks of the piece from its blocks.
+func (t Piece) computeMasks() []uint64 {
+	masks := make([]uint64, t.Height)
+
+	for _, p := range t.Pos {
+		masks[p.Y] |= 1 << p.X
+	}
+
+	return masks
+}
+
+// rowMasks returns the row bitmasks of the piece, computing them for pieces
+// that were not built through Init.
+func (t Piece) rowMasks() []uint64 {
+	if len(t.masks) != t.Height {
+		return t.computeMasks()
+	}
+
+	return t.masks
+}
+
+// comparePoints orders points row by row, the order Init reads blocks in.
+func comparePoints(a, b Point) int {
+	return cmp.Or(cmp.Compare(a.Y, b.Y), cmp.Compare(a.X, b.X))
+}
+
+// transformed returns a copy of the piece with every block mapped by f and renormalized.
+func (t Piece) transformed(f func(Point) Point) Piece {
+	out := t
+	out.Pos = make([]Point, len(t.Pos))
+
+	for i, p := range t.Pos {
+		out.Pos[i] = f(p)
+	}
+
+	out.normalize()
+	slices.SortFunc(out.Pos, comparePoints)
+	return out
+}
+
+// rotated returns the piece turned a quarter clockwise.
+func (t Piece) rotated() Piece {
+	return t.transformed(func(p Point) Point { return Point{X: -p.Y, Y: p.X} })
+}
+
+// mirrored returns the piece reflected across the vertical axis.
+func (t Piece) mirrored() Piece {
+	return t.transformed(func(p Point) Point { return Point{X: -p.X, Y: p.Y} })
 }
 
 //////////////////// PUBLIC METHODS ////////////////////
 
-// Init validates and normalizes a RawPiece (4 blocks, neighbour count 6 or 8).
-func Init(rawTet RawPiece, id byte) (Piece, error) {
-	var tet Piece
-	neighbours := 0
-	blockCount := 0
+// Orientations returns the distinct orientations allowed by t.Transforms,
+// starting with the piece as drawn. An O piece has one, an I piece at most two.
+func (t Piece) Orientations() []Piece {
+	candidates := []Piece{t}
 
-	for y, row := range rawTet {
-		for x, char := range row {
-			if char == '.' {
-				continue
-			}
+	if t.Transforms&Mirror != 0 {
+		candidates = append(candidates, t.mirrored())
+	}
 
-			if char != '#' {
-				return Piece{}, fmt.Errorf("unrecognised character '%c'", char)
+	if t.Transforms&Rotate != 0 {
+		for _, c := range slices.Clone(candidates) {
+			for range 3 {
+				c = c.rotated()
+				candidates = append(candidates, c)
 			}
+		}
+	}
 
-			if blockCount >= 4 {
-				return Piece{}, errors.New("tetromino should have 4 blocks.")
-			}
+	var distinct []Piece
+	seen := map[string]bool{}
+
+	for _, c := range candidates {
+		if key := ShapeKey(c.Pos); !seen[key] {
+			seen[key] = true
+			distinct = append(distinct, c)
+		}
+	}
+
+	return distinct
+}
 
-			pos := Point{X: x, Y: y}
-			neighbours += countNeighbors(pos, rawTet)
-			tet.Pos[blockCount] = pos
-			blockCount++
+// IdenticalGroups groups the indices of pieces that cover the same blocks in the
+// same allowed orientations, so that swapping them on a board gives another
+// solution. Groups are ordered by their first piece and hold at least one index
+// each, in increasing order.
+func IdenticalGroups(pieces []Piece) [][]int {
+	var groups [][]int
+	index := map[string]int{}
+
+	for i, p := range pieces {
+		keys := make([]string, 0, 8)
+		for _, o := range p.Orientations() {
+			keys = append(keys, ShapeKey(o.Pos))
 		}
+
+		slices.Sort(keys)
+		key := fmt.Sprint(keys)
+
+		g, ok := index[key]
+		if !ok {
+			g = len(groups)
+			index[key] = g
+			groups = append(groups, nil)
+		}
+
+		groups[g] = append(groups[g], i)
 	}
 
-	if blockCount != 4 {
-		return Piece{}, errors.New("tetromino should have 4 blocks")
+	return groups
+}
+
+// Init validates and normalizes a tetromino: a 4×4 RawPiece with 4 connected blocks.
+// It returns ErrGridSize, a *GlyphError, a *BlockCountError or a *DisconnectedError.
+func Init(rawTet RawPiece, id int) (Piece, error) {
+	if len(rawTet) != 4 || slices.ContainsFunc(rawTet, func(row []byte) bool { return len(row) != 4 }) {
+		return Piece{}, ErrGridSize
 	}
 
-	if neighbours != 6 && neighbours != 8 {
-		return Piece{}, errors.New("invalid tetromino")
+	blocks, err := scanBlocks(rawTet)
+	if err != nil {
+		return Piece{}, err
 	}
 
-	tet.ID = id
-	tet.normalize()
-	return tet, nil
+	if len(blocks) != 4 {
+		return Piece{}, &BlockCountError{Kind: KindTetromino, Count: len(blocks)}
+	}
+
+	if stray := strayBlocks(blocks); stray != nil {
+		return Piece{}, &DisconnectedError{Kind: KindTetromino, Stray: stray}
+	}
+
+	return newPiece(blocks, id), nil
+}
+
+// InitPolyomino validates and normalizes a RawPiece of any size holding a
+// single orthogonally connected shape of one or more blocks.
+// It returns a *GlyphError, a *BlockCountError or a *DisconnectedError.
+func InitPolyomino(raw RawPiece, id int) (Piece, error) {
+	blocks, err := scanBlocks(raw)
+	if err != nil {
+		return Piece{}, err
+	}
+
+	if len(blocks) == 0 {
+		return Piece{}, &BlockCountError{Kind: KindPolyomino}
+	}
+
+	if stray := strayBlocks(blocks); stray != nil {
+		return Piece{}, &DisconnectedError{Kind: KindPolyomino, Stray: stray}
+	}
+
+	return newPiece(blocks, id), nil
 }
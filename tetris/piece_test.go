@@ -1,6 +1,7 @@
 package tetris
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -12,36 +13,42 @@ func makeRaw(t *testing.T, rows ...string) RawPiece {
 		panic("invalid: number of rows for RawPiece")
 	}
 
-	var grid RawPiece
+	return makeGrid(rows...)
+}
+
+// Helper to create a Raw grid of any size from strings
+func makeGrid(rows ...string) RawPiece {
+	grid := make(RawPiece, len(rows))
 
 	for y, rowStr := range rows {
-		copy(grid[y][:], []byte(rowStr))
+		grid[y] = []byte(rowStr)
 	}
 
 	return grid
 }
 
 // Helper to create the expected Piece struct manually
-func makePiece(id byte, w, h int, coords ...Point) Piece {
-	var pos [4]Point
-
-	copy(pos[:], coords)
-	return Piece{
+func makePiece(id int, w, h int, coords ...Point) Piece {
+	p := Piece{
 		ID:     id,
 		Width:  w,
 		Height: h,
-		Pos:    pos,
+		Pos:    coords,
 	}
+
+	p.masks = p.computeMasks()
+	return p
 }
 
 func TestInit(t *testing.T) {
 	testData := []struct {
 		name      string
 		raw       RawPiece
-		tetID     byte
+		tetID     int
 		want      Piece
 		expectErr bool
-		errMsg    string
+		is        error // Sentinel the error should match
+		wantErr   error // Expected typed error
 	}{
 		{
 			name: "Valid I-Shape (Horizontal)",
@@ -108,7 +115,8 @@ func TestInit(t *testing.T) {
 				"....",
 			),
 			expectErr: true,
-			errMsg:    "unrecognised character 'x'",
+			is:        ErrGlyph,
+			wantErr:   &GlyphError{Glyph: 'x', Pos: Point{0, 2}},
 		},
 		{
 			name: "Invalid: Count (Too few)",
@@ -119,7 +127,8 @@ func TestInit(t *testing.T) {
 				"....",
 			),
 			expectErr: true,
-			errMsg:    "tetromino should have 4 blocks",
+			is:        ErrBlockCount,
+			wantErr:   &BlockCountError{Kind: KindTetromino, Count: 3},
 		},
 		{
 			name: "Invalid: Count (Too many)",
@@ -130,7 +139,8 @@ func TestInit(t *testing.T) {
 				"....",
 			),
 			expectErr: true,
-			errMsg:    "tetromino should have 4 blocks.",
+			is:        ErrBlockCount,
+			wantErr:   &BlockCountError{Kind: KindTetromino, Count: 5},
 		},
 		{
 			name: "Invalid: Connectivity (Isolated Block)",
@@ -141,7 +151,8 @@ func TestInit(t *testing.T) {
 				"....",
 			),
 			expectErr: true,
-			errMsg:    "invalid tetromino",
+			is:        ErrDisconnected,
+			wantErr:   &DisconnectedError{Kind: KindTetromino, Stray: []Point{{0, 2}}},
 		},
 		{
 			name: "Invalid: Connectivity (2 floating blocks)",
@@ -152,7 +163,8 @@ func TestInit(t *testing.T) {
 				"...#",
 			),
 			expectErr: true,
-			errMsg:    "invalid tetromino",
+			is:        ErrDisconnected,
+			wantErr:   &DisconnectedError{Kind: KindTetromino, Stray: []Point{{3, 2}, {3, 3}}},
 		},
 	}
 
@@ -161,10 +173,10 @@ func TestInit(t *testing.T) {
 			got, err := Init(test.raw, test.tetID)
 
 			if test.expectErr {
-				if err == nil {
-					t.Error("expected error but got nil")
-				} else if err.Error() != test.errMsg {
-					t.Errorf("expected error %q, got %q", test.errMsg, err.Error())
+				if !errors.Is(err, test.is) {
+					t.Errorf("expected error matching %v, got %v", test.is, err)
+				} else if !reflect.DeepEqual(err, test.wantErr) {
+					t.Errorf("expected error %+v, got %+v", test.wantErr, err)
 				}
 
 				return
@@ -180,3 +192,197 @@ func TestInit(t *testing.T) {
 		})
 	}
 }
+
+func TestOrientations(t *testing.T) {
+	testData := []struct {
+		name       string
+		raw        RawPiece
+		transforms Transform
+		expected   int
+	}{
+		{"I fixed", makeRaw(t, "####", "....", "....", "...."), 0, 1},
+		{"I rotated", makeRaw(t, "####", "....", "....", "...."), Rotate, 2},
+		{"O rotated and mirrored", makeRaw(t, "##..", "##..", "....", "...."), Rotate | Mirror, 1},
+		{"T rotated", makeRaw(t, "###.", ".#..", "....", "...."), Rotate, 4},
+		{"T mirrored", makeRaw(t, "###.", ".#..", "....", "...."), Mirror, 1},
+		{"S rotated", makeRaw(t, ".##.", "##..", "....", "...."), Rotate, 2},
+		{"S rotated and mirrored", makeRaw(t, ".##.", "##..", "....", "...."), Rotate | Mirror, 4},
+		{"L mirrored", makeRaw(t, "#...", "#...", "##..", "...."), Mirror, 2},
+		{"L rotated", makeRaw(t, "#...", "#...", "##..", "...."), Rotate, 4},
+		{"L rotated and mirrored", makeRaw(t, "#...", "#...", "##..", "...."), Rotate | Mirror, 8},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			piece, err := Init(test.raw, 'A')
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			piece.Transforms = test.transforms
+			orientations := piece.Orientations()
+
+			if len(orientations) != test.expected {
+				t.Fatalf("expected %d orientations, got %d: %+v", test.expected, len(orientations), orientations)
+			}
+
+			if !reflect.DeepEqual(orientations[0], piece) {
+				t.Errorf("expected first orientation to be the piece as drawn, got %+v", orientations[0])
+			}
+
+			for _, o := range orientations {
+				if o.ID != piece.ID {
+					t.Errorf("expected ID %c, got %c", piece.ID, o.ID)
+				}
+
+				if o.Width*o.Height != piece.Width*piece.Height {
+					t.Errorf("expected bounding box area %d, got %dx%d", piece.Width*piece.Height, o.Width, o.Height)
+				}
+			}
+		})
+	}
+
+	t.Run("Rotated L", func(t *testing.T) {
+		piece, _ := Init(makeRaw(t, "#...", "#...", "##..", "...."), 'A')
+		want := makePiece('A', 3, 2, Point{0, 0}, Point{1, 0}, Point{2, 0}, Point{0, 1})
+		want.Transforms = Rotate
+		piece.Transforms = Rotate
+
+		if got := piece.Orientations()[1]; !reflect.DeepEqual(got, want) {
+			t.Errorf("Orientations()[1] mismatch:\nGot:  %+v\nWant: %+v", got, want)
+		}
+	})
+}
+
+func TestIdenticalGroups(t *testing.T) {
+	horizontal := makePiece(0, 4, 1, Point{0, 0}, Point{1, 0}, Point{2, 0}, Point{3, 0})
+	vertical := makePiece(0, 1, 4, Point{0, 0}, Point{0, 1}, Point{0, 2}, Point{0, 3})
+	square := makePiece(0, 2, 2, Point{0, 0}, Point{1, 0}, Point{0, 1}, Point{1, 1})
+
+	rotated := func(p Piece) Piece {
+		p.Transforms = Rotate
+		return p
+	}
+
+	testData := []struct {
+		name     string
+		pieces   []Piece
+		expected [][]int
+	}{
+		{"Empty", nil, nil},
+		{"All distinct", []Piece{horizontal, vertical, square}, [][]int{{0}, {1}, {2}}},
+		{"Repeated", []Piece{square, horizontal, square, horizontal, square}, [][]int{{0, 2, 4}, {1, 3}}},
+		{"Drawn differently, rotated", []Piece{rotated(horizontal), rotated(vertical)}, [][]int{{0, 1}}},
+		{"Same blocks, different transforms", []Piece{horizontal, rotated(horizontal)}, [][]int{{0}, {1}}},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			if groups := IdenticalGroups(test.pieces); !reflect.DeepEqual(groups, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, groups)
+			}
+		})
+	}
+}
+
+func TestInitGridSize(t *testing.T) {
+	_, err := Init(makeGrid("##", "##"), 'A')
+
+	if !errors.Is(err, ErrGridSize) {
+		t.Errorf("expected ErrGridSize, got %v", err)
+	}
+}
+
+func TestInitPolyomino(t *testing.T) {
+	testData := []struct {
+		name      string
+		raw       RawPiece
+		want      Piece
+		expectErr bool
+		is        error // Sentinel the error should match
+		wantErr   error // Expected typed error
+	}{
+		{
+			name: "Valid monomino",
+			raw:  makeGrid("#"),
+			want: makePiece('A', 1, 1, Point{0, 0}),
+		},
+		{
+			name: "Valid I-pentomino",
+			raw:  makeGrid("#####"),
+			want: makePiece('A', 5, 1, Point{0, 0}, Point{1, 0}, Point{2, 0}, Point{3, 0}, Point{4, 0}),
+		},
+		{
+			name: "Valid F-pentomino (Shifted in grid)",
+			raw: makeGrid(
+				".....",
+				"..##.",
+				".##..",
+				"..#..",
+			),
+			want: makePiece('A', 3, 3, Point{1, 0}, Point{2, 0}, Point{0, 1}, Point{1, 1}, Point{1, 2}),
+		},
+		{
+			name: "Valid U-pentomino",
+			raw: makeGrid(
+				"###",
+				"#.#",
+			),
+			want: makePiece('A', 3, 2, Point{0, 0}, Point{1, 0}, Point{2, 0}, Point{0, 1}, Point{2, 1}),
+		},
+		{
+			name:      "Invalid: empty grid",
+			raw:       makeGrid("...", "..."),
+			expectErr: true,
+			is:        ErrBlockCount,
+			wantErr:   &BlockCountError{Kind: KindPolyomino},
+		},
+		{
+			name:      "Invalid: diagonal only",
+			raw:       makeGrid("#.", ".#"),
+			expectErr: true,
+			is:        ErrDisconnected,
+			wantErr:   &DisconnectedError{Kind: KindPolyomino, Stray: []Point{{1, 1}}},
+		},
+		{
+			name:      "Invalid: character",
+			raw:       makeGrid("#x"),
+			expectErr: true,
+			is:        ErrGlyph,
+			wantErr:   &GlyphError{Glyph: 'x', Pos: Point{1, 0}},
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := InitPolyomino(test.raw, 'A')
+
+			if test.expectErr {
+				if !errors.Is(err, test.is) {
+					t.Errorf("expected error matching %v, got %v", test.is, err)
+				} else if !reflect.DeepEqual(err, test.wantErr) {
+					t.Errorf("expected error %+v, got %+v", test.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("InitPolyomino() mismatch:\nGot:  %+v\nWant: %+v", got, test.want)
+			}
+		})
+	}
+
+	t.Run("F-pentomino orientations", func(t *testing.T) {
+		piece, _ := InitPolyomino(makeGrid(".##", "##.", ".#."), 'A')
+		piece.Transforms = Rotate | Mirror
+
+		if n := len(piece.Orientations()); n != 8 {
+			t.Errorf("expected 8 orientations, got %d", n)
+		}
+	})
+}
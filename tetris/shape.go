@@ -0,0 +1,138 @@
+// Package tetris contains the classification of pieces into the seven tetrominoes.
+package tetris
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Shape identifies one of the seven tetrominoes.
+type Shape uint8
+
+const (
+	NoShape Shape = iota // Not a tetromino
+	ShapeI
+	ShapeO
+	ShapeT
+	ShapeS
+	ShapeZ
+	ShapeJ
+	ShapeL
+)
+
+// shapeNames holds the letter of each shape, indexed by Shape.
+var shapeNames = [...]string{"?", "I", "O", "T", "S", "Z", "J", "L"}
+
+// String returns the letter of the shape, or "?" for NoShape.
+func (s Shape) String() string {
+	if int(s) >= len(shapeNames) {
+		return shapeNames[NoShape]
+	}
+
+	return shapeNames[s]
+}
+
+// spawnShapes draws orientation 0 of each shape, flat side down where it has one.
+var spawnShapes = map[Shape][]string{
+	ShapeI: {"####"},
+	ShapeO: {"##", "##"},
+	ShapeT: {".#.", "###"},
+	ShapeS: {".##", "##."},
+	ShapeZ: {"##.", ".##"},
+	ShapeJ: {"#..", "###"},
+	ShapeL: {"..#", "###"},
+}
+
+// classification is the shape of a set of blocks and its quarter turns from orientation 0.
+type classification struct {
+	shape Shape
+	turns int
+}
+
+// shapeTable maps the blocks of every orientation of every shape to its classification.
+var shapeTable = buildShapeTable()
+
+// NormalizedBlocks returns a copy of blocks shifted to start at (0, 0) and sorted row by row.
+func NormalizedBlocks(blocks []Point) []Point {
+	minX, minY := blocks[0].X, blocks[0].Y
+	for _, b := range blocks {
+		minX, minY = min(minX, b.X), min(minY, b.Y)
+	}
+
+	out := make([]Point, len(blocks))
+	for i, b := range blocks {
+		out[i] = Point{X: b.X - minX, Y: b.Y - minY}
+	}
+
+	slices.SortFunc(out, comparePoints)
+	return out
+}
+
+// ShapeKey returns a map key for the shape of blocks, which does not depend on
+// their order or position, so equal keys mean one translates onto the other.
+func ShapeKey(blocks []Point) string {
+	return fmt.Sprint(NormalizedBlocks(blocks))
+}
+
+// buildShapeTable indexes the orientations of every shape.
+func buildShapeTable() map[string]classification {
+	table := map[string]classification{}
+
+	for shape := range spawnShapes {
+		for turns, p := range shape.Orientations() {
+			table[ShapeKey(p.Pos)] = classification{shape: shape, turns: turns}
+		}
+	}
+
+	return table
+}
+
+// Classify returns the shape of the piece as drawn, and its orientation as the
+// number of clockwise quarter turns from orientation 0 of the shape. I, S and Z
+// pieces have orientations 0 and 1, O pieces only 0, and the others 0 to 3.
+// Pieces that are not tetrominoes are NoShape.
+func (t Piece) Classify() (Shape, int) {
+	c, ok := shapeTable[ShapeKey(t.Pos)]
+	if !ok {
+		return NoShape, 0
+	}
+
+	return c.shape, c.turns
+}
+
+// Orientations returns the distinct orientations of the shape, indexed by the
+// number of clockwise quarter turns as reported by Classify, with ID 0.
+// NoShape has none.
+func (s Shape) Orientations() []Piece {
+	rows, ok := spawnShapes[s]
+	if !ok {
+		return nil
+	}
+
+	raw := make(RawPiece, len(rows))
+	for y, row := range rows {
+		raw[y] = []byte(row)
+	}
+
+	p, _ := InitPolyomino(raw, 0)
+	p.Transforms = Rotate
+
+	orientations := p.Orientations()
+	for i := range orientations {
+		orientations[i].Transforms = 0
+	}
+
+	return orientations
+}
+
+// CountShapes returns how many pieces there are of each shape, NoShape included.
+func CountShapes(pieces []Piece) map[Shape]int {
+	counts := map[Shape]int{}
+
+	for _, p := range pieces {
+		shape, _ := p.Classify()
+		counts[shape]++
+	}
+
+	return counts
+}
@@ -0,0 +1,139 @@
+package tetris
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	testData := []struct {
+		name        string
+		raw         RawPiece
+		shape       Shape
+		orientation int
+	}{
+		{"I horizontal", makeGrid("####"), ShapeI, 0},
+		{"I vertical", makeGrid("#", "#", "#", "#"), ShapeI, 1},
+		{"O", makeGrid("##", "##"), ShapeO, 0},
+		{"T up", makeGrid(".#.", "###"), ShapeT, 0},
+		{"T right", makeGrid("#.", "##", "#."), ShapeT, 1},
+		{"T down", makeGrid("###", ".#."), ShapeT, 2},
+		{"T left", makeGrid(".#", "##", ".#"), ShapeT, 3},
+		{"S", makeGrid(".##", "##."), ShapeS, 0},
+		{"S vertical", makeGrid("#.", "##", ".#"), ShapeS, 1},
+		{"Z", makeGrid("##.", ".##"), ShapeZ, 0},
+		{"Z vertical", makeGrid(".#", "##", "#."), ShapeZ, 1},
+		{"J", makeGrid("#..", "###"), ShapeJ, 0},
+		{"J down", makeGrid("###", "..#"), ShapeJ, 2},
+		{"L", makeGrid("..#", "###"), ShapeL, 0},
+		{"L upright", makeGrid("#.", "#.", "##"), ShapeL, 1},
+		{"Position in the grid does not matter", makeGrid("....", "..#.", ".###", "...."), ShapeT, 0},
+		{"Pentomino", makeGrid("#####"), NoShape, 0},
+		{"Tromino", makeGrid("##", "#."), NoShape, 0},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			piece, err := InitPolyomino(test.raw, 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			shape, orientation := piece.Classify()
+			if shape != test.shape || orientation != test.orientation {
+				t.Errorf("expected %v in orientation %d, got %v in orientation %d",
+					test.shape, test.orientation, shape, orientation)
+			}
+		})
+	}
+}
+
+func TestClassifyOrientations(t *testing.T) {
+	// Every orientation of every shape keeps its shape, and rotations number them in turn.
+	for shape, rows := range spawnShapes {
+		piece, _ := InitPolyomino(makeGrid(rows...), 0)
+		piece.Transforms = Rotate
+
+		for i, o := range piece.Orientations() {
+			got, orientation := o.Classify()
+			if got != shape || orientation != i {
+				t.Errorf("%v orientation %d: got %v in orientation %d", shape, i, got, orientation)
+			}
+		}
+	}
+}
+
+func TestCountShapes(t *testing.T) {
+	var pieces []Piece
+
+	for _, rows := range [][]string{{"####"}, {".#.", "###"}, {"###", ".#."}, {"#"}} {
+		p, _ := InitPolyomino(makeGrid(rows...), len(pieces))
+		pieces = append(pieces, p)
+	}
+
+	expected := map[Shape]int{ShapeI: 1, ShapeT: 2, NoShape: 1}
+	if counts := CountShapes(pieces); !maps.Equal(counts, expected) {
+		t.Errorf("expected %v, got %v", expected, counts)
+	}
+}
+
+func TestShapeKey(t *testing.T) {
+	blocks := []Point{{5, 8}, {4, 7}, {3, 7}, {4, 8}}
+
+	expected := []Point{{0, 0}, {1, 0}, {1, 1}, {2, 1}}
+	if got := NormalizedBlocks(blocks); !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if blocks[0] != (Point{5, 8}) {
+		t.Errorf("expected the blocks to be left unchanged, got %v", blocks)
+	}
+
+	testData := []struct {
+		name  string
+		other []Point
+		equal bool
+	}{
+		{"Translated and reordered", []Point{{1, 1}, {0, 0}, {2, 1}, {1, 0}}, true},
+		{"Mirrored", []Point{{1, 0}, {2, 0}, {0, 1}, {1, 1}}, false},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			if equal := ShapeKey(blocks) == ShapeKey(test.other); equal != test.equal {
+				t.Errorf("expected equal keys to be %v for %v and %v", test.equal, blocks, test.other)
+			}
+		})
+	}
+}
+
+func TestShapeString(t *testing.T) {
+	if s := ShapeL.String() + NoShape.String() + Shape(42).String(); s != "L??" {
+		t.Errorf("expected \"L??\", got %q", s)
+	}
+}
+
+func TestShapeOrientations(t *testing.T) {
+	testData := []struct {
+		shape    Shape
+		expected int
+	}{
+		{NoShape, 0}, {ShapeI, 2}, {ShapeO, 1}, {ShapeT, 4}, {ShapeS, 2}, {ShapeZ, 2}, {ShapeJ, 4}, {ShapeL, 4},
+	}
+
+	for _, test := range testData {
+		t.Run(test.shape.String(), func(t *testing.T) {
+			orientations := test.shape.Orientations()
+			if len(orientations) != test.expected {
+				t.Fatalf("expected %d orientations, got %d", test.expected, len(orientations))
+			}
+
+			for turns, p := range orientations {
+				if shape, got := p.Classify(); shape != test.shape || got != turns {
+					t.Errorf("expected %v turned %d times, got %v turned %d times", test.shape, turns, shape, got)
+				}
+			}
+		})
+	}
+}
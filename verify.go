@@ -0,0 +1,198 @@
+// Package main contains the verify subcommand for checking solutions and certificates.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"tetris-optimizer/tetris"
+)
+
+// verifyOptions holds the parsed verify command line.
+type verifyOptions struct {
+	polyomino    bool
+	transforms   tetris.Transform // Symmetries the pieces may appear under
+	alphabet     string
+	piecesPath   string
+	solutionPath string
+	certPath     string // Empty when only the solution is checked
+}
+
+// parseVerifyArgs parses the verify arguments (without the subcommand name).
+func parseVerifyArgs(args []string) (verifyOptions, error) {
+	var opts verifyOptions
+
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.BoolVar(&opts.polyomino, "polyomino", false, "accept polyominoes of any size")
+	flags.StringVar(&opts.alphabet, "alphabet", tetris.DefaultAlphabet, "piece labels in the solution")
+	rotate := flags.Bool("rotate", false, "accept rotated pieces")
+	mirror := flags.Bool("mirror", false, "accept mirrored pieces")
+
+	if err := flags.Parse(args); err != nil {
+		return opts, err
+	}
+
+	if *rotate {
+		opts.transforms |= tetris.Rotate
+	}
+
+	if *mirror {
+		opts.transforms |= tetris.Mirror
+	}
+
+	if flags.NArg() != 2 && flags.NArg() != 3 {
+		return opts, errors.New("expected a pieces file, a solution file and an optional certificate file")
+	}
+
+	if _, err := tetris.Labels(opts.alphabet); err != nil {
+		return opts, err
+	}
+
+	opts.piecesPath, opts.solutionPath, opts.certPath = flags.Arg(0), flags.Arg(1), flags.Arg(2)
+	return opts, nil
+}
+
+// checkSolution checks that cells holds every piece exactly once, each in one of its
+// allowed orientations. labels names the pieces in errors.
+func checkSolution(pieces []tetris.Piece, cells map[int][]tetris.Point, labels []rune) error {
+	for id := range cells {
+		if id >= len(pieces) {
+			return fmt.Errorf("label '%c' does not match any piece", labels[id])
+		}
+	}
+
+	for id, p := range pieces {
+		if len(cells[id]) == 0 {
+			return fmt.Errorf("piece '%c' is missing", labels[id])
+		}
+
+		if n := len(p.Pos); len(cells[id]) > n && len(cells[id])%n == 0 {
+			return fmt.Errorf("piece '%c' appears %d times", labels[id], len(cells[id])/n)
+		}
+
+		placed := tetris.ShapeKey(cells[id])
+		matches := slices.ContainsFunc(p.Orientations(), func(o tetris.Piece) bool {
+			return tetris.ShapeKey(o.Pos) == placed
+		})
+
+		if !matches {
+			return fmt.Errorf("piece '%c' does not match its shape", labels[id])
+		}
+	}
+
+	return nil
+}
+
+// describeCertificate summarises why the certificate rules out the smaller square.
+func describeCertificate(c Certificate) string {
+	switch c.Reason {
+	case ReasonArea:
+		return "the pieces cover more cells than it has"
+	case ReasonLength:
+		return "a piece is longer than its side"
+	default:
+		return fmt.Sprintf("refuted in %d nodes with %d dead ends", len(c.Tree), c.Leaves)
+	}
+}
+
+// describeMinimum compares the size of a solution with the theoretical minimum for its pieces.
+func describeMinimum(pieces []tetris.Piece, size int) string {
+	minSize := minimumBoardSize(pieceCells(pieces))
+
+	if size == minSize {
+		return fmt.Sprintf("Size %d matches the theoretical minimum", size)
+	}
+
+	return fmt.Sprintf("Size %d is above the theoretical minimum of %d", size, minSize)
+}
+
+// runVerify checks a solution, and its certificate if given, against a pieces file
+// independently of the solvers, and writes a report to w.
+func runVerify(opts verifyOptions, w io.Writer) error {
+	piecesFile, err := os.Open(opts.piecesPath)
+	if err != nil {
+		return err
+	}
+
+	defer piecesFile.Close()
+	pieces, err := readPieces(piecesFile, opts.polyomino, len([]rune(opts.alphabet)), ParseOptions{})
+	if err != nil {
+		return err
+	}
+
+	solutionFile, err := os.Open(opts.solutionPath)
+	if err != nil {
+		return err
+	}
+
+	defer solutionFile.Close()
+	solution, err := tetris.ParseBoardAlphabet(solutionFile, opts.alphabet)
+	if err != nil {
+		return err
+	}
+
+	size := solution.Width
+
+	// Solutions fill a plain square, so blocked cells only come from a container
+	// and would hide the cells the pieces leave empty.
+	for y := range size {
+		for x := range size {
+			if solution.Blocked(x, y) {
+				return fmt.Errorf("invalid solution; line %d, column %d: blocked cell '#'", y+1, x+1)
+			}
+		}
+	}
+
+	var cert Certificate
+
+	if opts.certPath != "" {
+		if cert, err = readCertificateFile(opts.certPath); err != nil {
+			return err
+		}
+
+		if cert.Transforms != opts.transforms {
+			return fmt.Errorf("certificate allows transforms %s, not %s",
+				formatTransforms(cert.Transforms), formatTransforms(opts.transforms))
+		}
+	}
+
+	pieces = withTransforms(pieces, opts.transforms)
+	if err := checkSolution(pieces, solution.Cells(), []rune(opts.alphabet)); err != nil {
+		return fmt.Errorf("invalid solution; %w", err)
+	}
+
+	fmt.Fprintf(w, "Solution is valid: %d pieces in a %d×%d square\n", len(pieces), size, size)
+	fmt.Fprintln(w, describeMinimum(pieces, size))
+
+	if opts.certPath == "" {
+		return nil
+	}
+
+	if cert.Size != size {
+		return fmt.Errorf("certificate is for size %d, solution has size %d", cert.Size, size)
+	}
+
+	if err := VerifyCertificate(pieces, cert); err != nil {
+		return fmt.Errorf("invalid certificate; %w", err)
+	}
+
+	fmt.Fprintf(w, "Certificate is valid: size %d is infeasible, %s\n", size-1, describeCertificate(cert))
+
+	return nil
+}
+
+// readCertificateFile parses the certificate file at path.
+func readCertificateFile(path string) (Certificate, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Certificate{}, err
+	}
+
+	defer file.Close()
+	return ReadCertificate(file)
+}
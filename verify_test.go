@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tetris-optimizer/tetris"
+)
+
+func TestCheckSolution(t *testing.T) {
+	pieces := loadPieces(t, "tests/good_examples/goodexample01-09")
+	labels := []rune(tetris.DefaultAlphabet)
+	testData := []struct {
+		name       string
+		board      string
+		transforms tetris.Transform
+		errorMsg   string
+	}{
+		{"Valid", "ABBBB\nA.CCC\nA.DDC\nADD..\n.....\n", 0, ""},
+		{"Missing piece", "ABBBB\nA.CCC\nA...C\nA....\n.....\n", 0, "piece 'D' is missing"},
+		{"Duplicated piece", "ABBBB\nA.CCC\nA.DDC\nADD..\nBBBB.\n", 0, "piece 'B' appears 2 times"},
+		{"Extra cell", "ABBBB\nA.CCC\nA.DDC\nADD..\n....B\n", 0, "piece 'B' does not match its shape"},
+		{"Wrong shape", "ABBBB\nA.CCC\nA.DD.\nADD.C\n.....\n", 0, "piece 'C' does not match its shape"},
+		{"Unknown piece", "ABBBB\nA.CCC\nA.DDC\nADD..\n...EE\n", 0, "label 'E' does not match any piece"},
+		{"Rotated without rotation", "AAAAB\n....B\nCCC.B\n..C.B\nDD.DD\n", 0, "piece 'A' does not match its shape"},
+		{"Rotated with rotation", "AAAAC\nBBBBC\n.DDCC\nDD...\n.....\n", tetris.Rotate, ""},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			board, err := tetris.ParseBoard(strings.NewReader(test.board))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err = checkSolution(withTransforms(pieces, test.transforms), board.Cells(), labels)
+			if test.errorMsg == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || err.Error() != test.errorMsg {
+				t.Fatalf("expected error %q, got %v", test.errorMsg, err)
+			}
+		})
+	}
+}
+
+// runVerifyArgs parses args and runs the verify subcommand with them.
+func runVerifyArgs(args []string, w io.Writer) error {
+	opts, err := parseVerifyArgs(args)
+	if err != nil {
+		return err
+	}
+
+	return runVerify(opts, w)
+}
+
+func TestRunVerify(t *testing.T) {
+	const piecesPath = "tests/good_examples/goodexample01-09"
+
+	dir := t.TempDir()
+	pieces := loadPieces(t, piecesPath)
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+
+		return path
+	}
+
+	board, err := FindSmallestSquare(context.Background(), pieces, newSolver(defaultOptions(nil)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cert, err := Certify(context.Background(), pieces, board.Width)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCertificate(&buf, cert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	certPath := write("cert", buf.String())
+	solutionPath := write("solution", board.ToString())
+
+	t.Run("Valid", func(t *testing.T) {
+		var out bytes.Buffer
+
+		if err := runVerifyArgs([]string{piecesPath, solutionPath, certPath}, &out); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := "Solution is valid: 4 pieces in a 5×5 square\n" +
+			"Size 5 is above the theoretical minimum of 4\n" +
+			"Certificate is valid: size 4 is infeasible, refuted in 5 nodes with 4 dead ends\n"
+		if out.String() != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, out.String())
+		}
+	})
+
+	t.Run("Without certificate", func(t *testing.T) {
+		var out bytes.Buffer
+
+		if err := runVerifyArgs([]string{"tests/samples/sample00-04", write("sample", loadSolution(t))}, &out); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := "Solution is valid: 8 pieces in a 6×6 square\n" +
+			"Size 6 matches the theoretical minimum\n"
+		if out.String() != expected {
+			t.Fatalf("expected:\n%s\ngot:\n%s", expected, out.String())
+		}
+	})
+
+	testData := []struct {
+		name     string
+		args     []string
+		errorMsg string
+	}{
+		{"Missing solution argument", []string{piecesPath}, ""},
+		{"Ragged solution", []string{piecesPath, write("ragged", "AB\nA\n")}, "invalid board; rows should have equal length"},
+		{
+			"Transforms differ from certificate", []string{"--rotate", piecesPath, solutionPath, certPath},
+			"certificate allows transforms none, not rotate",
+		},
+		{
+			"Certificate loosens transforms", []string{
+				write("bars", strings.Repeat("####\n....\n....\n....\n\n", 3)+"####\n....\n....\n....\n"),
+				write("upright", "ABCD\nABCD\nABCD\nABCD\n"),
+				write("forged", certificateHeader+"\nsize 4\ntransforms rotate\nreason area\n"),
+			},
+			"certificate allows transforms rotate, not none",
+		},
+		{
+			"Rotated solution", []string{piecesPath, write("rotated", "AAAAC\nBBBBC\n.DDCC\nDD...\n.....\n")},
+			"invalid solution; piece 'A' does not match its shape",
+		},
+		{
+			"Larger solution", []string{piecesPath, write("large", "ABBBB.\nA.CCC.\nA.DDC.\nADD...\n......\n......\n"), certPath},
+			"certificate is for size 5, solution has size 6",
+		},
+		{
+			"Tampered certificate", []string{piecesPath, solutionPath, write("bad", strings.Replace(buf.String(), "reason search", "reason area", 1))},
+			"invalid certificate; 16 cells fit in the area of a square of size 4",
+		},
+		{
+			"Blocked cells", []string{piecesPath, write("blocked", "ACCC#\nA..C#\nA.DD#\nADD.#\nBBBB#\n")},
+			"invalid solution; line 1, column 5: blocked cell '#'",
+		},
+		{
+			"Invalid solution", []string{piecesPath, write("wrong", "ABBBB\nA.CCC\nA...C\nA....\n.....\n"), certPath},
+			"invalid solution; piece 'D' is missing",
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			err := runVerifyArgs(test.args, &bytes.Buffer{})
+			if err == nil {
+				t.Fatal("expected error but got nil")
+			}
+
+			if test.errorMsg != "" && err.Error() != test.errorMsg {
+				t.Errorf("expected error %q, got %q", test.errorMsg, err.Error())
+			}
+		})
+	}
+}
+
+// loadSolution returns the expected solution of tests/samples/sample00-04.
+func loadSolution(t *testing.T) string {
+	t.Helper()
+
+	board, err := FindSmallestSquare(context.Background(), loadPieces(t, "tests/samples/sample00-04"), newSolver(defaultOptions(nil)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	return board.ToString()
+}
+
+func TestParseVerifyArgs(t *testing.T) {
+	testData := []struct {
+		name        string
+		args        []string
+		expected    verifyOptions
+		expectError bool
+	}{
+		{
+			"Solution only", []string{"pieces", "solution"},
+			verifyOptions{alphabet: tetris.DefaultAlphabet, piecesPath: "pieces", solutionPath: "solution"}, false,
+		},
+		{
+			"With certificate", []string{"--polyomino", "pieces", "solution", "cert"},
+			verifyOptions{
+				polyomino: true, alphabet: tetris.DefaultAlphabet,
+				piecesPath: "pieces", solutionPath: "solution", certPath: "cert",
+			}, false,
+		},
+		{
+			"Rotate and mirror", []string{"--rotate", "--mirror", "pieces", "solution"},
+			verifyOptions{
+				transforms: tetris.Rotate | tetris.Mirror, alphabet: tetris.DefaultAlphabet,
+				piecesPath: "pieces", solutionPath: "solution",
+			}, false,
+		},
+		{"Too few paths", []string{"pieces"}, verifyOptions{}, true},
+		{"Too many paths", []string{"a", "b", "c", "d"}, verifyOptions{}, true},
+		{"Invalid alphabet", []string{"--alphabet", "A.", "pieces", "solution"}, verifyOptions{}, true},
+		{"Whitespace alphabet", []string{"--alphabet", "AB C", "pieces", "solution"}, verifyOptions{}, true},
+	}
+
+	for _, test := range testData {
+		t.Run(test.name, func(t *testing.T) {
+			opts, err := parseVerifyArgs(test.args)
+			if test.expectError {
+				if err == nil {
+					t.Errorf("expected error, got options %+v", opts)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if opts != test.expected {
+				t.Errorf("expected %+v, got %+v", test.expected, opts)
+			}
+		})
+	}
+}